@@ -1,533 +1,7044 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
 	"encoding/csv"
+	"encoding/gob"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gopkg.in/yaml.v3"
 )
 
 // ChronoData represents the data we need to save/load for each chronometer
 type ChronoData struct {
-	ID           int           `json:"id"`
-	DisplayLabel string        `json:"displayLabel"`
-	ElapsedTime  time.Duration `json:"elapsedTime"`
-	IsRunning    bool          `json:"isRunning"`
+	ID            int             `json:"id" yaml:"id"`
+	DisplayLabel  string          `json:"displayLabel" yaml:"displayLabel"`
+	ElapsedTime   time.Duration   `json:"elapsedTime" yaml:"elapsedTime"`
+	IsRunning     bool            `json:"isRunning" yaml:"isRunning"`
+	Budget        time.Duration   `json:"budget,omitempty" yaml:"budget,omitempty"`
+	Selected      bool            `json:"selected,omitempty" yaml:"selected,omitempty"`
+	Segments      []Segment       `json:"segments,omitempty" yaml:"segments,omitempty"`
+	Notes         []Note          `json:"notes,omitempty" yaml:"notes,omitempty"`
+	StartCount    int             `json:"startCount,omitempty" yaml:"startCount,omitempty"`
+	OvertimeAt    time.Duration   `json:"overtimeAt,omitempty" yaml:"overtimeAt,omitempty"`
+	ScheduledAt   time.Time       `json:"scheduledAt,omitzero" yaml:"scheduledAt,omitempty"`
+	Goal          time.Duration   `json:"goal,omitempty" yaml:"goal,omitempty"`
+	GoalMetOn     string          `json:"goalMetOn,omitempty" yaml:"goalMetOn,omitempty"`
+	Mode          ChronoMode      `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Target        time.Duration   `json:"target,omitempty" yaml:"target,omitempty"`
+	Laps          []time.Duration `json:"laps,omitempty" yaml:"laps,omitempty"`
+	Splits        []time.Duration `json:"splits,omitempty" yaml:"splits,omitempty"`
+	Paused        bool            `json:"paused,omitempty" yaml:"paused,omitempty"`
+	PomodoroPhase string          `json:"pomodoroPhase,omitempty" yaml:"pomodoroPhase,omitempty"`
+	Pomodoros     int             `json:"pomodoros,omitempty" yaml:"pomodoros,omitempty"`
+	Category      string          `json:"category,omitempty" yaml:"category,omitempty"`
+	MaxDuration   time.Duration   `json:"maxDuration,omitempty" yaml:"maxDuration,omitempty"`
+	StartedAt     time.Time       `json:"startedAt,omitzero" yaml:"startedAt,omitempty"`
+	StoppedAt     time.Time       `json:"stoppedAt,omitzero" yaml:"stoppedAt,omitempty"`
+}
+
+// Note is a timestamped free-text note attached to a chronometer
+type Note struct {
+	Time time.Time `json:"time" yaml:"time"`
+	Text string    `json:"text" yaml:"text"`
+}
+
+// Segment records one continuous run of a chronometer. End is zero while
+// the run is still in progress.
+type Segment struct {
+	Start time.Time `json:"start" yaml:"start"`
+	End   time.Time `json:"end,omitzero" yaml:"end,omitempty"`
 }
 
 // SaveData represents all chronometers for saving/loading
 type SaveData struct {
-	Chronometers []ChronoData `json:"chronometers"`
-	SaveTime     time.Time    `json:"saveTime"`
+	Chronometers []ChronoData  `json:"chronometers" yaml:"chronometers"`
+	SaveTime     time.Time     `json:"saveTime" yaml:"saveTime"`
+	Indicators   string        `json:"indicators,omitempty" yaml:"indicators,omitempty"`
+	TimeZone     string        `json:"timeZone,omitempty" yaml:"timeZone,omitempty"`
+	TotalElapsed time.Duration `json:"totalElapsed" yaml:"totalElapsed"` // informational; not read back
+	SelectedID   int           `json:"selectedID,omitempty" yaml:"selectedID,omitempty"`
+}
+
+// ChronoMode selects whether a chronometer counts up or down
+type ChronoMode int
+
+const (
+	ChronoModeStopwatch ChronoMode = iota
+	ChronoModeCountdown
+)
+
+// Pomodoro phases. A Pomodoro timer is a countdown that switches between
+// them by itself when one runs out.
+const (
+	PomodoroWork  = "work"
+	PomodoroBreak = "break"
+)
+
+// State transitions reported to OnStateChange callbacks
+const (
+	EventStart = "start"
+	EventStop  = "stop"
+	EventReset = "reset"
+)
+
+// stateChange is a transition a chronometer went through, held until the
+// manager passes it on to the OnStateChange callbacks
+type stateChange struct {
+	event string
+	at    time.Time
+}
+
+// pomodoroLengths is how long each Pomodoro phase counts down
+var pomodoroLengths = map[string]time.Duration{
+	PomodoroWork:  25 * time.Minute,
+	PomodoroBreak: 5 * time.Minute,
 }
 
 type Chronometer struct {
 	startTime    time.Time
 	elapsedTime  time.Duration
 	isRunning    bool
+	paused       bool // stopped by Pause, to be continued by Resume
 	displayLabel string
 	id           int
+	budget       time.Duration
+	selected     bool
+	mode         ChronoMode
+	target       time.Duration
+	laps         []time.Duration
+	splits       []time.Duration // GetElapsedTime at each lap
+	segments     []Segment
+	notes        []Note
+	startCount   int
+	overtimeAt   time.Duration
+	scheduledAt  time.Time
+	tapping      bool
+	goal         time.Duration
+	goalMetOn    string // local date the goal was last met, as 2006-01-02
+	// pomodoroPhase is PomodoroWork or PomodoroBreak for a Pomodoro timer
+	// and empty otherwise; pomodoros counts the work phases completed
+	pomodoroPhase string
+	pomodoros     int
+	// category groups timers for reporting, e.g. by project
+	category string
+	// maxDuration is the counted time at which the chronometer stops by
+	// itself; zero means no limit
+	maxDuration time.Duration
+	// lastElapsed and lastRunning are the state before the last Reset, kept
+	// for UndoReset while undoable
+	lastElapsed time.Duration
+	lastRunning bool
+	undoable    bool
+	// startedAt and stoppedAt are the wall-clock times of the last start
+	// and stop; zero if there was none since the last reset
+	startedAt time.Time
+	stoppedAt time.Time
+	// lastInteraction is when the chronometer was last started or touched
+	// in the UI; idleStopped is set when the manager stopped it for going
+	// untouched too long, until it is started or reset
+	lastInteraction time.Time
+	idleStopped     bool
+	// epoch is bumped whenever the displayed time jumps on purpose (reset,
+	// target change, load) so display guards know to resync
+	epoch int
+	// changes are the transitions not yet reported by the manager
+	changes []stateChange
+}
+
+// defaultLabel returns the label a chronometer starts out with
+func defaultLabel(id int) string {
+	return fmt.Sprintf("Timer %d", id)
 }
 
 func NewChronometer(id int) *Chronometer {
 	return &Chronometer{
 		elapsedTime:  0,
 		isRunning:    false,
-		displayLabel: fmt.Sprintf("Timer %d", id),
+		displayLabel: defaultLabel(id),
 		id:           id,
 	}
 }
 
 func (c *Chronometer) Start() {
+	c.scheduledAt = time.Time{}
+	c.paused = false
 	if !c.isRunning {
-		c.startTime = time.Now().Add(-c.elapsedTime)
-		c.isRunning = true
+		now := time.Now()
+		c.startRun(now)
+		c.startCount++
+		c.startedAt = now
+		c.lastInteraction = now
+		c.idleStopped = false
 	}
 }
 
+// beginRun starts a run at now, counting on from the elapsed time. Start
+// and Reset both go through it so a running timer's start time is always
+// derived the same way.
+func (c *Chronometer) beginRun(now time.Time) {
+	c.startTime = now.Add(-c.elapsedTime)
+	c.isRunning = true
+	c.segments = append(c.segments, Segment{Start: now})
+}
+
+// startRun begins a run at now as beginRun does and reports it as a start.
+// Start, Resume and UndoReset share it; only Start counts a new start.
+func (c *Chronometer) startRun(now time.Time) {
+	c.beginRun(now)
+	c.changes = append(c.changes, stateChange{EventStart, now})
+}
+
 func (c *Chronometer) Stop() {
+	c.paused = false
 	if c.isRunning {
-		c.elapsedTime = time.Since(c.startTime)
+		end := time.Now()
+		c.elapsedTime = end.Sub(c.startTime)
+		if c.maxDuration > 0 && c.elapsedTime > c.maxDuration {
+			// The maximum was reached before the manager noticed, so the
+			// run ends there, as expire would have ended it
+			c.elapsedTime = c.maxDuration
+			end = c.startTime.Add(c.maxDuration)
+			if n := len(c.segments); n > 0 && end.Before(c.segments[n-1].Start) {
+				end = c.segments[n-1].Start
+			}
+		}
 		c.isRunning = false
+		c.closeSegment(end)
+		c.stoppedAt = end
+		c.changes = append(c.changes, stateChange{EventStop, end})
 	}
 }
 
+// Reset clears the elapsed time and history. A running chronometer keeps
+// running from zero, starting a fresh run without counting another start.
+// The time cleared is kept for UndoReset.
 func (c *Chronometer) Reset() {
+	c.lastElapsed, c.lastRunning, c.undoable = c.elapsed(), c.isRunning, true
+	running := c.isRunning
+	c.isRunning = false
 	c.elapsedTime = 0
+	c.paused = false
+	c.epoch++
+	c.segments = nil
+	c.scheduledAt = time.Time{}
+	c.laps = nil
+	c.splits = nil
+	c.tapping = false
+	c.idleStopped = false
+	now := time.Now()
+	c.startedAt, c.stoppedAt = time.Time{}, time.Time{}
+	if running {
+		c.beginRun(now)
+		c.startedAt = now
+	}
+	c.changes = append(c.changes, stateChange{EventReset, now})
+}
+
+// UndoReset adds the time cleared by the last Reset back on top of any
+// counted since, and runs the chronometer again if it was running then. Only
+// the most recent reset can be undone, once; it reports whether there was
+// one. Laps and run history cleared by the reset stay cleared.
+func (c *Chronometer) UndoReset() bool {
+	if !c.undoable {
+		return false
+	}
+	c.undoable = false
+	c.epoch++
 	if c.isRunning {
-		c.startTime = time.Now()
+		c.startTime = c.startTime.Add(-c.lastElapsed)
+		return true
+	}
+	c.elapsedTime += c.lastElapsed
+	if c.lastRunning {
+		now := time.Now()
+		c.paused = false
+		c.startRun(now)
+		c.startedAt = now
 	}
+	return true
 }
 
-func (c *Chronometer) GetElapsedTime() time.Duration {
+// Restart clears the chronometer as Reset does and runs it from zero. A
+// chronometer that wasn't running counts a start.
+func (c *Chronometer) Restart() {
+	c.Reset()
+	c.Start()
+}
+
+// Pause stops a running chronometer, marking it as paused rather than
+// stopped until Resume, Start, Stop or Reset
+func (c *Chronometer) Pause() {
 	if c.isRunning {
-		return time.Since(c.startTime)
+		c.Stop()
+		c.paused = true
 	}
-	return c.elapsedTime
 }
 
-func formatDuration(d time.Duration) string {
-	hours := int(d.Hours())
-	minutes := int(d.Minutes()) % 60
-	seconds := int(d.Seconds()) % 60
-	milliseconds := int(d.Milliseconds()) % 1000
+// Resume continues a paused chronometer from its accumulated time. Unlike
+// Start it counts no new start and keeps the time the run first started
+// and any scheduled start. It does nothing unless paused.
+func (c *Chronometer) Resume() {
+	if c.paused && !c.isRunning {
+		now := time.Now()
+		c.paused = false
+		c.startRun(now)
+		c.lastInteraction = now
+	}
+}
 
-	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, milliseconds)
+// IsPaused reports whether the chronometer was paused
+func (c *Chronometer) IsPaused() bool {
+	return c.paused
 }
 
-func parseDuration(s string) (time.Duration, error) {
-	// Split by : and .
-	parts := strings.Split(s, ":")
-	if len(parts) != 3 {
-		return 0, fmt.Errorf("invalid time format")
+// closeSegment ends the open run segment, if any, at the given time
+func (c *Chronometer) closeSegment(at time.Time) {
+	if n := len(c.segments); n > 0 && c.segments[n-1].End.IsZero() {
+		c.segments[n-1].End = at
 	}
+}
 
-	secParts := strings.Split(parts[2], ".")
-	if len(secParts) != 2 {
-		return 0, fmt.Errorf("invalid seconds format")
+// elapsed returns the time counted up so far, regardless of the mode. A
+// running chronometer never counts past its maximum duration, even before
+// expire stops it.
+func (c *Chronometer) elapsed() time.Duration {
+	if c.isRunning {
+		if d := time.Since(c.startTime); c.maxDuration <= 0 || d < c.maxDuration {
+			return d
+		}
+		return c.maxDuration
 	}
+	return c.elapsedTime
+}
 
-	// Parse each part
-	hours, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return 0, err
+// limit returns the counted time at which a running chronometer stops by
+// itself, the earlier of its countdown target and its maximum duration, or
+// zero if there is none
+func (c *Chronometer) limit() time.Duration {
+	limit := c.maxDuration
+	if c.mode == ChronoModeCountdown && (limit <= 0 || c.target < limit) {
+		limit = c.target
 	}
+	return limit
+}
 
-	minutes, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return 0, err
+// progressFraction is how far the chronometer has counted towards its
+// countdown target or maximum duration, whichever comes first, from 0 to 1.
+// It is 0 for a chronometer with neither.
+func progressFraction(c *Chronometer) float64 {
+	limit := c.limit()
+	if limit <= 0 {
+		return 0
 	}
+	return min(float64(c.elapsed())/float64(limit), 1)
+}
 
-	seconds, err := strconv.Atoi(secParts[0])
-	if err != nil {
-		return 0, err
+// renderProgressBar draws fraction as a bar of width cells followed by the
+// percentage, e.g. "█████░░░░░  50%"
+func renderProgressBar(fraction float64, width int, filledColor string) string {
+	cells := max(width-5, 1)
+	filled := int(fraction * float64(cells))
+	return fmt.Sprintf("%s%s[gray]%s[-]%4.0f%%", colorTag(filledColor), strings.Repeat("█", filled),
+		strings.Repeat("░", cells-filled), fraction*100)
+}
+
+// expire stops a running countdown that has reached zero, or a chronometer
+// that has reached its maximum duration, as if it had been stopped at that
+// exact moment. It reports whether it stopped it.
+func (c *Chronometer) expire(now time.Time) bool {
+	limit := c.limit()
+	if !c.isRunning || limit <= 0 || now.Sub(c.startTime) < limit {
+		return false
+	}
+	end := c.startTime.Add(limit)
+	if n := len(c.segments); n > 0 && end.Before(c.segments[n-1].Start) {
+		// Started beyond the limit already
+		end = c.segments[n-1].Start
 	}
+	c.elapsedTime = limit
+	c.isRunning = false
+	c.closeSegment(end)
+	c.stoppedAt = end
+	c.changes = append(c.changes, stateChange{EventStop, end})
+	return true
+}
 
-	millis, err := strconv.Atoi(secParts[1])
-	if err != nil {
-		return 0, err
+// SetMaxDuration sets the counted time at which the chronometer stops by
+// itself, clamped to exactly that time. A zero duration removes the limit.
+func (c *Chronometer) SetMaxDuration(d time.Duration) {
+	if d < 0 {
+		d = 0
 	}
+	c.maxDuration = d
+}
 
-	// Calculate total duration
-	duration := time.Duration(hours)*time.Hour +
-		time.Duration(minutes)*time.Minute +
-		time.Duration(seconds)*time.Second +
-		time.Duration(millis)*time.Millisecond
+// GetElapsedTime returns the elapsed time, or the remaining time for a
+// countdown. The remaining time never goes below zero.
+func (c *Chronometer) GetElapsedTime() time.Duration {
+	elapsed := c.elapsed()
+	if c.mode == ChronoModeCountdown {
+		if elapsed >= c.target {
+			return 0
+		}
+		return c.target - elapsed
+	}
+	return elapsed
+}
 
-	return duration, nil
+// FormattedElapsed returns GetElapsedTime as HH:MM:SS.mmm, live while the
+// chronometer runs
+func (c *Chronometer) FormattedElapsed() string {
+	return formatDuration(c.GetElapsedTime())
 }
 
-type ChronoManager struct {
-	chronometers []*Chronometer
-	mutex        sync.Mutex
+// FormattedElapsedWithDays is FormattedElapsed with a day count from 24
+// hours on, as in "2d 03:04:05.678"
+func (c *Chronometer) FormattedElapsedWithDays() string {
+	return formatDurationWithDays(c.GetElapsedTime())
 }
 
-func NewChronoManager(count int) *ChronoManager {
-	cm := &ChronoManager{
-		chronometers: make([]*Chronometer, count),
+// ID returns the chronometer's 1-based number
+func (c *Chronometer) ID() int {
+	return c.id
+}
+
+// SetElapsed sets the counted time, e.g. to make up for a timer started
+// late. A running chronometer counts on from d. The value is kept between
+// zero and the maximum duration; the run history is left as it is.
+func (c *Chronometer) SetElapsed(d time.Duration) {
+	d = max(d, 0)
+	if c.maxDuration > 0 {
+		d = min(d, c.maxDuration)
 	}
-	for i := 0; i < count; i++ {
-		cm.chronometers[i] = NewChronometer(i + 1)
+	c.epoch++
+	if c.isRunning {
+		c.startTime = time.Now().Add(-d)
+		return
 	}
-	return cm
+	c.elapsedTime = d
 }
 
-func (cm *ChronoManager) StartChronometer(id int) {
-	cm.mutex.Lock()
-	defer cm.mutex.Unlock()
+// StartedAt returns the wall-clock time the chronometer was last started,
+// zero if it hasn't been since it was created or reset
+func (c *Chronometer) StartedAt() time.Time {
+	return c.startedAt
+}
 
-	// Stop all running chronometers
-	for _, c := range cm.chronometers {
-		if c.isRunning {
-			c.Stop()
-		}
+// StoppedAt returns the wall-clock time the chronometer was last stopped,
+// zero if it hasn't been since it was created or reset
+func (c *Chronometer) StoppedAt() time.Time {
+	return c.stoppedAt
+}
+
+// formatClock formats t as a time of day, or "-" for the zero time
+func formatClock(t time.Time) string {
+	if t.IsZero() {
+		return "-"
 	}
+	return t.Format("15:04:05")
+}
 
-	// Start the selected chronometer
-	if id >= 0 && id < len(cm.chronometers) {
-		cm.chronometers[id].Start()
+// DisplayLabel returns the chronometer's label. Like the other getters it
+// does not lock, so call it on the copies ChronoManager.Get and
+// Chronometers return rather than on a timer the manager may change.
+func (c *Chronometer) DisplayLabel() string {
+	return c.displayLabel
+}
+
+// SetLabel sets the label with surrounding whitespace trimmed. An empty
+// label is rejected and the old one kept.
+func (c *Chronometer) SetLabel(s string) error {
+	label := strings.TrimSpace(s)
+	if label == "" {
+		return fmt.Errorf("label must not be empty")
 	}
+	c.displayLabel = label
+	return nil
 }
 
-func (cm *ChronoManager) SaveToFile(filename string) error {
-	data := SaveData{
-		Chronometers: make([]ChronoData, len(cm.chronometers)),
-		SaveTime:     time.Now(),
+// Category returns the chronometer's category, empty if it has none
+func (c *Chronometer) Category() string {
+	return c.category
+}
+
+// SetCategory sets the category with surrounding whitespace trimmed. An
+// empty category removes it.
+func (c *Chronometer) SetCategory(category string) {
+	c.category = strings.TrimSpace(category)
+}
+
+// IsRunning reports whether the chronometer is running
+func (c *Chronometer) IsRunning() bool {
+	return c.isRunning
+}
+
+// SetTarget turns the chronometer into a countdown from d. A zero target
+// switches it back to a stopwatch. Either way it leaves Pomodoro mode.
+func (c *Chronometer) SetTarget(d time.Duration) {
+	c.epoch++
+	c.pomodoroPhase = ""
+	if d <= 0 {
+		c.mode = ChronoModeStopwatch
+		c.target = 0
+		return
+	}
+	c.mode = ChronoModeCountdown
+	c.target = d
+}
+
+// AdjustTarget moves a countdown's target by delta without stopping it.
+// The remaining time is clamped so it never goes negative.
+func (c *Chronometer) AdjustTarget(delta time.Duration) {
+	if c.mode != ChronoModeCountdown {
+		return
 	}
+	c.epoch++
+	c.target += delta
+	if elapsed := c.elapsed(); c.target < elapsed {
+		c.target = elapsed
+	}
+}
 
-	for i, c := range cm.chronometers {
-		data.Chronometers[i] = ChronoData{
-			ID:           c.id,
-			DisplayLabel: c.displayLabel,
-			ElapsedTime:  c.GetElapsedTime(),
-			IsRunning:    c.isRunning,
-		}
+// setPomodoroPhase starts phase from zero at the given time, counting it
+// down from its length. A chronometer that isn't running stays stopped.
+func (c *Chronometer) setPomodoroPhase(phase string, at time.Time) {
+	running := c.isRunning
+	if running {
+		c.closeSegment(at)
+		c.isRunning = false
+	}
+	c.SetTarget(pomodoroLengths[phase])
+	c.pomodoroPhase = phase
+	c.elapsedTime = 0
+	c.paused = false
+	if running {
+		c.beginRun(at)
 	}
+}
 
-	jsonData, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return err
+// nextPomodoroPhase returns the phase following the current one
+func (c *Chronometer) nextPomodoroPhase() string {
+	if c.pomodoroPhase == PomodoroWork {
+		return PomodoroBreak
 	}
+	return PomodoroWork
+}
 
-	return ioutil.WriteFile(filename, jsonData, 0644)
+// PomodoroPhase returns PomodoroWork or PomodoroBreak, or "" unless the
+// chronometer is a Pomodoro timer
+func (c *Chronometer) PomodoroPhase() string {
+	return c.pomodoroPhase
 }
 
-func (cm *ChronoManager) LoadFromFile(filename string) error {
-	jsonData, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
+// Pomodoros returns the number of work phases run to the end
+func (c *Chronometer) Pomodoros() int {
+	return c.pomodoros
+}
+
+// snapshot returns a copy of the chronometer's persistable state
+func (c *Chronometer) snapshot() ChronoData {
+	return ChronoData{
+		ID:            c.id,
+		DisplayLabel:  c.displayLabel,
+		ElapsedTime:   c.elapsed(),
+		IsRunning:     c.isRunning,
+		Budget:        c.budget,
+		Selected:      c.selected,
+		Segments:      append([]Segment(nil), c.segments...),
+		Notes:         append([]Note(nil), c.notes...),
+		StartCount:    c.startCount,
+		OvertimeAt:    c.overtimeAt,
+		ScheduledAt:   c.scheduledAt,
+		Goal:          c.goal,
+		GoalMetOn:     c.goalMetOn,
+		Mode:          c.mode,
+		Target:        c.target,
+		Laps:          c.GetLaps(),
+		Splits:        c.GetSplits(),
+		Paused:        c.paused,
+		PomodoroPhase: c.pomodoroPhase,
+		Pomodoros:     c.pomodoros,
+		Category:      c.category,
+		MaxDuration:   c.maxDuration,
+		StartedAt:     c.startedAt,
+		StoppedAt:     c.stoppedAt,
 	}
+}
 
-	var data SaveData
-	if err := json.Unmarshal(jsonData, &data); err != nil {
-		return err
+// clone returns a copy of c that shares none of its slices, so either may
+// be changed without affecting the other. Transitions not yet reported stay
+// with c.
+func (c *Chronometer) clone() *Chronometer {
+	copied := *c
+	copied.laps = append([]time.Duration(nil), c.laps...)
+	copied.splits = append([]time.Duration(nil), c.splits...)
+	copied.segments = append([]Segment(nil), c.segments...)
+	copied.notes = append([]Note(nil), c.notes...)
+	copied.changes = nil
+	return &copied
+}
+
+// SetOvertimeAt sets the elapsed time after which a stopwatch is shown as
+// running over. A zero value disables it.
+func (c *Chronometer) SetOvertimeAt(d time.Duration) {
+	if d < 0 {
+		d = 0
 	}
+	c.overtimeAt = d
+}
 
-	// Stop all running chronometers first
-	for _, c := range cm.chronometers {
-		c.Stop()
+// overtime returns how far a stopwatch has run past its overtime mark
+func (c *Chronometer) overtime() time.Duration {
+	if c.mode != ChronoModeStopwatch || c.overtimeAt <= 0 {
+		return 0
 	}
+	if over := c.GetElapsedTime() - c.overtimeAt; over > 0 {
+		return over
+	}
+	return 0
+}
 
-	// Update chronometer states
-	for _, cd := range data.Chronometers {
-		// Find the corresponding chronometer by ID
-		for i, c := range cm.chronometers {
-			if c.id == cd.ID {
-				cm.chronometers[i].displayLabel = cd.DisplayLabel
-				cm.chronometers[i].elapsedTime = cd.ElapsedTime
-				// If it was running, start it again
-				if cd.IsRunning {
-					cm.chronometers[i].Start()
-				}
-				break
-			}
+// utilization returns the share of wall-clock time since the first
+// recorded start that the chronometer spent running, from 0 to 1, or 0 if
+// it has no recorded runs
+func (c *Chronometer) utilization(now time.Time) float64 {
+	if len(c.segments) == 0 {
+		return 0
+	}
+	var running time.Duration
+	for _, seg := range c.segments {
+		end := seg.End
+		if end.IsZero() {
+			end = now
 		}
+		running += end.Sub(seg.Start)
+	}
+	span := now.Sub(c.segments[0].Start)
+	if span <= 0 || running >= span {
+		return 1
 	}
+	return float64(running) / float64(span)
+}
 
-	return nil
+// SetGoal sets the running time the chronometer should reach each day. A
+// zero goal disables it.
+func (c *Chronometer) SetGoal(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	c.goal = d
 }
 
-func (cm *ChronoManager) SaveToCSV(filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
+// todayElapsed returns how long the chronometer has run since local
+// midnight, from its recorded segments
+func (c *Chronometer) todayElapsed(now time.Time) time.Duration {
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	var total time.Duration
+	for _, seg := range c.segments {
+		start, end := seg.Start, seg.End
+		if end.IsZero() {
+			end = now
+		}
+		if start.Before(midnight) {
+			start = midnight
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
 	}
-	defer file.Close()
+	return total
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// goalMet reports whether the daily goal has been reached today. Once met
+// it stays met until local midnight, even if the chronometer is reset.
+func (c *Chronometer) goalMet(now time.Time) bool {
+	if c.goal <= 0 {
+		return false
+	}
+	today := now.Format("2006-01-02")
+	if c.goalMetOn == today {
+		return true
+	}
+	if c.todayElapsed(now) >= c.goal {
+		c.goalMetOn = today
+		return true
+	}
+	return false
+}
 
-	// Write header
-	if err := writer.Write([]string{"Timer ID", "Label", "Elapsed Time"}); err != nil {
-		return err
+// Lap records the time since the previous lap, or since the start for the
+// first one, and the split, the GetElapsedTime reading at the lap, without
+// stopping the chronometer. On a stopped chronometer it records up to the
+// frozen elapsed time. It returns the lap time.
+func (c *Chronometer) Lap() time.Duration {
+	lap := c.elapsed()
+	for _, previous := range c.laps {
+		lap -= previous
 	}
+	c.laps = append(c.laps, lap)
+	c.splits = append(c.splits, c.GetElapsedTime())
+	return lap
+}
 
-	// Write data
-	for _, c := range cm.chronometers {
-		elapsed := formatDuration(c.GetElapsedTime())
-		if err := writer.Write([]string{
-			fmt.Sprintf("%d", c.id),
-			c.displayLabel,
-			elapsed,
-		}); err != nil {
-			return err
+// GetLaps returns a copy of the recorded lap times
+func (c *Chronometer) GetLaps() []time.Duration {
+	laps := make([]time.Duration, len(c.laps))
+	copy(laps, c.laps)
+	return laps
+}
+
+// GetSplits returns a copy of the recorded splits, one per lap
+func (c *Chronometer) GetSplits() []time.Duration {
+	splits := make([]time.Duration, len(c.splits))
+	copy(splits, c.splits)
+	return splits
+}
+
+// splitsOf rebuilds the splits of laps read from a file that holds no
+// splits, as a stopwatch counts them
+func splitsOf(laps []time.Duration) []time.Duration {
+	var splits []time.Duration
+	var total time.Duration
+	for _, lap := range laps {
+		total += lap
+		splits = append(splits, total)
+	}
+	return splits
+}
+
+// LapStats returns the shortest, mean and longest lap and the sample
+// standard deviation of the laps. All are zero with fewer than two laps.
+func (c *Chronometer) LapStats() (shortest, avg, longest, stddev time.Duration) {
+	n := len(c.laps)
+	if n < 2 {
+		return 0, 0, 0, 0
+	}
+
+	shortest, longest = c.laps[0], c.laps[0]
+	var sum float64
+	for _, lap := range c.laps {
+		if lap < shortest {
+			shortest = lap
+		}
+		if lap > longest {
+			longest = lap
 		}
+		sum += float64(lap)
 	}
+	mean := sum / float64(n)
 
-	return nil
+	var squares float64
+	for _, lap := range c.laps {
+		squares += (float64(lap) - mean) * (float64(lap) - mean)
+	}
+	return shortest, time.Duration(math.Round(mean)), longest, time.Duration(math.Round(math.Sqrt(squares / float64(n-1))))
 }
 
-func main() {
-	app := tview.NewApplication()
+// SetBudget sets the time budget for the chronometer. A zero budget disables it.
+func (c *Chronometer) SetBudget(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	c.budget = d
+}
 
-	// Create chronometer manager with 15 chronometers
-	manager := NewChronoManager(15)
+func formatDuration(d time.Duration) string {
+	return formatDurationPrecision(d, 3)
+}
 
-	// Main layout grid
-	grid := tview.NewGrid().
-		SetRows(0, 3). // Main area for chronometers, 3 rows for buttons
-		SetColumns(0)
+// formatDurationPrecision formats d as HH:MM:SS followed by precision
+// fractional digits of the second (none for 0, up to 9)
+func formatDurationPrecision(d time.Duration, precision int) string {
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	seconds := int(d.Seconds()) % 60
 
-	// Create a grid for chronometers: 3 columns, 5 rows
-	chronoGrid := tview.NewGrid().
-		SetRows(0, 0, 0, 0, 0).
-		SetColumns(0, 0, 0)
+	formatted := fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+	if precision <= 0 {
+		return formatted
+	}
+	if precision > 9 {
+		precision = 9
+	}
 
-	chronometersUI := make([]*tview.Flex, 15)
-	statusTexts := make([]*tview.TextView, 15)
-	labelInputs := make([]*tview.InputField, 15)
-
-	// Create UI for each chronometer
-	for i := 0; i < 15; i++ {
-		chron := manager.chronometers[i]
-		chronUI := tview.NewFlex().SetDirection(tview.FlexRow)
-
-		// Label input for this chronometer
-		labelInput := tview.NewInputField().
-			SetLabel("Label: ").
-			SetText(chron.displayLabel).
-			SetFieldWidth(80).
-			SetDoneFunc(func(key tcell.Key) {
-				// This will be set properly below
-			})
+	fraction := int64(d % time.Second)
+	for i := precision; i < 9; i++ {
+		fraction /= 10
+	}
+	return fmt.Sprintf("%s.%0*d", formatted, precision, fraction)
+}
 
-		// Store for later reference
-		labelInputs[i] = labelInput
+// formatDurationWithDays formats d like formatDuration, but splits off whole
+// days from 24 hours on, e.g. "4d 04:00:00.000" rather than "100:00:00.000"
+func formatDurationWithDays(d time.Duration) string {
+	return formatDurationWithDaysPrecision(d, 3)
+}
 
-		// Timer display
-		timeText := tview.NewTextView().
-			SetTextAlign(tview.AlignCenter).
-			SetDynamicColors(true).
-			SetText("[yellow]00:00:00.000")
+// formatDurationWithDaysPrecision is formatDurationWithDays with precision
+// fractional digits, as for formatDurationPrecision
+func formatDurationWithDaysPrecision(d time.Duration, precision int) string {
+	if d < 24*time.Hour {
+		return formatDurationPrecision(d, precision)
+	}
+	days := int64(d / (24 * time.Hour))
+	return fmt.Sprintf("%dd %s", days, formatDurationPrecision(d%(24*time.Hour), precision))
+}
 
-		// Timer buttons
-		buttonFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+// RefreshInterval is how often the timers are redrawn while one is running.
+// Zero matches it to the display precision; see refreshInterval.
+var RefreshInterval time.Duration
 
-		// Get the ID for button callbacks
-		id := i // Important: Create a new variable to capture the current value of i
+// refreshInterval returns RefreshInterval or, if that is zero, the time one
+// step of the last displayed digit takes, kept between 10ms and 1s
+func refreshInterval(digits int) time.Duration {
+	if RefreshInterval > 0 {
+		return RefreshInterval
+	}
+	interval := time.Second
+	for i := 0; i < digits && interval > 10*time.Millisecond; i++ {
+		interval /= 10
+	}
+	return interval
+}
 
-		startButton := tview.NewButton("Start").SetSelectedFunc(func() {
-			manager.StartChronometer(id)
-		}).SetLabelColor(tcell.ColorGreen)
+// precisionNames lists the display precisions in the order the UI cycles
+// through them, mapped to their number of fractional digits
+var precisionNames = []string{"seconds", "centiseconds", "milliseconds", "microseconds"}
 
-		stopButton := tview.NewButton("Stop").SetSelectedFunc(func() {
-			manager.chronometers[id].Stop()
-		})
+var precisionDigits = map[string]int{
+	"seconds":      0,
+	"centiseconds": 2,
+	"milliseconds": 3,
+	"microseconds": 6,
+}
 
-		resetButton := tview.NewButton("Reset").SetSelectedFunc(func() {
-			manager.chronometers[id].Reset()
-		})
+// formatStartsIn renders the wait before a scheduled start as MM:SS, or
+// H:MM:SS from an hour up, rounding up to whole seconds
+func formatStartsIn(d time.Duration) string {
+	secs := int((d + time.Second - 1) / time.Second)
+	if secs < 0 {
+		secs = 0
+	}
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%02d:%02d", secs/60, secs%60)
+}
 
-		startButton.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
-			if action == tview.MouseLeftClick {
-				manager.StartChronometer(id)
-			}
-			return action, event
-		})
+// parseStartAt parses when a delayed start should fire: a time of day as
+// HH:MM (the next occurrence) or a delay such as "10m"
+func parseStartAt(s string, now time.Time) (time.Time, error) {
+	if hour, minute, err := parseClock(s); err == nil {
+		return nextDailyAt(now, hour, minute), nil
+	}
+	d, err := parseHuman(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid start time %q: use HH:MM or a delay such as 10m", s)
+	}
+	return now.Add(d), nil
+}
 
-		stopButton.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
-			if action == tview.MouseLeftClick {
-				manager.chronometers[id].Stop()
-			}
-			return action, event
-		})
+// formatDayCounter renders durations of 24 hours or more as a day-segmented
+// readout such as "Day 2 03:14:22". Shorter durations use formatDuration.
+func formatDayCounter(d time.Duration) string {
+	if d < 24*time.Hour {
+		return formatDuration(d)
+	}
+	days := int(d / (24 * time.Hour))
+	rest := d % (24 * time.Hour)
+	return fmt.Sprintf("Day %d %02d:%02d:%02d", days+1, int(rest.Hours()), int(rest.Minutes())%60, int(rest.Seconds())%60)
+}
 
-		resetButton.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
-			if action == tview.MouseLeftClick {
-				manager.chronometers[id].Reset()
-			}
-			return action, event
-		})
+// displayGuard keeps the displayed time of a running chronometer from ticking
+// backward when the sampled time wobbles, e.g. under clock adjustments. It
+// resyncs when the chronometer stops or its epoch changes.
+type displayGuard struct {
+	last  time.Duration
+	epoch int
+	valid bool
+}
 
-		buttonFlex.AddItem(startButton, 0, 1, false)
-		buttonFlex.AddItem(stopButton, 0, 1, false)
-		buttonFlex.AddItem(resetButton, 0, 1, false)
+// apply returns the value to display for the sampled value. Stopwatches never
+// go down and countdowns never go up while running within the same epoch.
+func (g *displayGuard) apply(value time.Duration, running, countdown bool, epoch int) time.Duration {
+	if !running || !g.valid || epoch != g.epoch {
+		g.last, g.epoch, g.valid = value, epoch, running
+		return value
+	}
 
-		// Status text
-		statusText := tview.NewTextView().
-			SetTextAlign(tview.AlignCenter).
-			SetText("Status: Stopped")
+	if (!countdown && value < g.last) || (countdown && value > g.last) {
+		return g.last
+	}
+	g.last = value
+	return value
+}
 
-		statusTexts[i] = statusText
+// formatBudget renders the budget line shown below the elapsed time,
+// e.g. "/ 04:00:00.000 (01:50:00.000 left)" or "(over by 00:05:00.000)".
+func formatBudget(elapsed, budget time.Duration) string {
+	remaining := budget - elapsed
+	if remaining < 0 {
+		return fmt.Sprintf("/ %s (over by %s)", formatDuration(budget), formatDuration(-remaining))
+	}
+	return fmt.Sprintf("/ %s (%s left)", formatDuration(budget), formatDuration(remaining))
+}
 
-		// Add components to chronometer UI
-		chronUI.AddItem(labelInput, 3, 0, true).
-			AddItem(timeText, 3, 0, false).
-			AddItem(buttonFlex, 3, 0, false).
-			AddItem(statusText, 1, 0, false)
+var (
+	// ErrInvalidTimeFormat is wrapped by the errors of parseDuration
+	ErrInvalidTimeFormat = errors.New("invalid time format")
+	// ErrTimerNotFound is wrapped by manager methods given an unknown timer
+	ErrTimerNotFound = errors.New("timer not found")
+)
 
-		chronUI.SetBorder(true).SetTitle(fmt.Sprintf(" Timer %d ", i+1))
-		chronometersUI[i] = chronUI
+// ParseError reports a value that could not be read from a save or CSV
+// file: the field it was meant for, the raw text and why it was rejected.
+// Line is the 1-based line of a CSV file, or 0 when there is none.
+type ParseError struct {
+	Line  int
+	Field string
+	Raw   string
+	Err   error
+}
 
-		// Add to the grid - calculate row and column
-		col := i % 3
-		row := i / 3
-		chronoGrid.AddItem(chronUI, row, col, 1, 1, 0, 0, false)
+func (e *ParseError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: invalid %s %q: %v", e.Line, e.Field, e.Raw, e.Err)
 	}
+	return fmt.Sprintf("invalid %s %q: %v", e.Field, e.Raw, e.Err)
+}
 
-	// Now that we have all the input fields, set their proper DoneFunc
-	for i, labelInput := range labelInputs {
-		// Create a closure with the correct id
-		id := i
-		labelInput.SetDoneFunc(func(key tcell.Key) {
-			manager.chronometers[id].displayLabel = labelInput.GetText()
-		})
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// csvParseError places err, as returned by parseDuration or strconv, at a
+// line and field of a CSV file
+func csvParseError(line int, field, raw string, err error) *ParseError {
+	var pe *ParseError
+	var ne *strconv.NumError
+	switch {
+	case errors.As(err, &pe):
+		err = pe.Err
+	case errors.As(err, &ne):
+		err = ne.Err
 	}
+	return &ParseError{Line: line, Field: field, Raw: raw, Err: err}
+}
 
-	// Button panel at the bottom
-	buttonPanel := tview.NewFlex().SetDirection(tview.FlexColumn)
+// parseDuration parses the HH:MM:SS[.fff] form formatDuration writes, with
+// optional days as either "DD:" or the "Nd " of formatDurationWithDays.
+// Hours may exceed 23, minutes and seconds must be below 60, and the
+// fraction may have up to nine digits.
+func parseDuration(s string) (time.Duration, error) {
+	// Read "Nd HH:MM:SS" as "N:HH:MM:SS"
+	text := s
+	days, clock, hasDays := strings.Cut(s, "d ")
+	if hasDays {
+		text = days + ":" + clock
+	}
 
-	// Save button
-	saveButton := tview.NewButton("Save").SetSelectedFunc(func() {
-		form := tview.NewForm()
-		form.AddInputField("Filename", "timers.json", 20, nil, nil)
-		form.AddButton("Save", func() {
-			filename := form.GetFormItem(0).(*tview.InputField).GetText()
-			err := manager.SaveToFile(filename)
-			var modalText string
-			if err != nil {
-				modalText = fmt.Sprintf("Error saving: %v", err)
-			} else {
-				modalText = fmt.Sprintf("Successfully saved to %s", filename)
-			}
+	// Split by : and .
+	parts := strings.Split(text, ":")
+	if len(parts) != 3 && len(parts) != 4 || hasDays && len(parts) != 4 {
+		return 0, &ParseError{Field: "duration", Raw: s, Err: fmt.Errorf("%w: want [Nd ]HH:MM:SS[.mmm]", ErrInvalidTimeFormat)}
+	}
 
-			modal := tview.NewModal().
-				SetText(modalText).
-				AddButtons([]string{"OK"}).
-				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-					app.SetRoot(grid, true)
+	fraction := ""
+	last := len(parts) - 1
+	if secParts := strings.SplitN(parts[last], ".", 2); len(secParts) == 2 {
+		parts[last], fraction = secParts[0], secParts[1]
+		if fraction == "" || len(fraction) > 9 || !allDigits(fraction) {
+			return 0, &ParseError{Field: "duration", Raw: s, Err: fmt.Errorf("%w: bad fraction of a second", ErrInvalidTimeFormat)}
+		}
+	}
+
+	// Parse each part, the days first if present
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	if len(parts) == 4 {
+		units = append([]time.Duration{24 * time.Hour}, units...)
+	}
+	var duration time.Duration
+	for i, part := range parts {
+		if part == "" || !allDigits(part) {
+			return 0, &ParseError{Field: "duration", Raw: s, Err: fmt.Errorf("%w: want [Nd ]HH:MM:SS[.mmm]", ErrInvalidTimeFormat)}
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || time.Duration(n) > (math.MaxInt64-duration)/units[i] {
+			return 0, &ParseError{Field: "duration", Raw: s, Err: fmt.Errorf("%w: out of range", ErrInvalidTimeFormat)}
+		}
+		if i >= len(parts)-2 && n >= 60 {
+			return 0, &ParseError{Field: "duration", Raw: s, Err: fmt.Errorf("%w: minutes and seconds must be below 60", ErrInvalidTimeFormat)}
+		}
+		duration += time.Duration(n) * units[i]
+	}
+
+	// The fraction is read as decimal digits of a second, so ".5" is half
+	// a second
+	if fraction != "" {
+		nanos, _ := strconv.Atoi(fraction + strings.Repeat("0", 9-len(fraction)))
+		if duration > math.MaxInt64-time.Duration(nanos) {
+			return 0, &ParseError{Field: "duration", Raw: s, Err: fmt.Errorf("%w: out of range", ErrInvalidTimeFormat)}
+		}
+		duration += time.Duration(nanos)
+	}
+
+	return duration, nil
+}
+
+// allDigits reports whether s consists of ASCII digits only
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// humanDurationPattern matches a single "<number><unit>" term such as
+// "2.5h", "90 min" or "5m".
+var humanDurationPattern = regexp.MustCompile(`(\d+(?:\.\d+)?|\.\d+)\s*([a-zA-Z]+)`)
+
+var humanDurationUnits = map[string]time.Duration{
+	"ms": time.Millisecond, "msec": time.Millisecond, "millisecond": time.Millisecond, "milliseconds": time.Millisecond,
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+}
+
+// parseHuman parses durations like "1h30m", "90 min", "2.5h" or "1h 5m".
+// Input containing a colon is handed to the strict parseDuration instead.
+func parseHuman(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ":") {
+		return parseDuration(s)
+	}
+
+	matches := humanDurationPattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var total time.Duration
+	pos := 0
+	for _, m := range matches {
+		// Only whitespace may separate the terms
+		if strings.TrimSpace(s[pos:m[0]]) != "" {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		value, err := strconv.ParseFloat(s[m[2]:m[3]], 64)
+		if err != nil {
+			return 0, err
+		}
+
+		unit, ok := humanDurationUnits[strings.ToLower(s[m[4]:m[5]])]
+		if !ok {
+			return 0, fmt.Errorf("unknown duration unit %q", s[m[4]:m[5]])
+		}
+
+		total += time.Duration(value * float64(unit))
+		pos = m[1]
+	}
+
+	if strings.TrimSpace(s[pos:]) != "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return total, nil
+}
+
+// DefaultMaxTimers is the default upper bound on the number of chronometers.
+// Beyond it the grid and the redraw loop stop being usable.
+const DefaultMaxTimers = 100
+
+// DefaultTimers is the number of chronometers created at startup
+const DefaultTimers = 15
+
+// maxCycledColumns is the largest column count the columns key cycles to
+// before going back to the automatic layout
+const maxCycledColumns = 6
+
+// gridColumns returns the number of grid columns for n timers: three, as
+// the grid always had, until the square root rounded down grows past that
+// and keeps large grids roughly square. Fewer timers than three get a
+// column each, and none at all still one.
+func gridColumns(n int) int {
+	cols := 1
+	for (cols+1)*(cols+1) <= n {
+		cols++
+	}
+	return min(max(n, 1), max(3, cols))
+}
+
+// ChronoManager holds the chronometers in display order. Methods taking
+// an index address a timer by its current position; RemoveChronometer, Get
+// and IndexOf take its 1-based ID instead, which never changes.
+type ChronoManager struct {
+	chronometers []*Chronometer
+	mutex        sync.Mutex
+	sessionStart time.Time
+	indicators   string
+	maxTimers    int
+	loadWarnings []string
+	allowDup     bool
+	uniqueLabels bool
+	location     *time.Location
+	resetStarts  bool
+	exportDigits int
+	exclusive    bool
+	current      int
+	nextID       int // the ID AddChronometer gives the next timer
+	saveDir      string
+	// idleStop is how long a running chronometer may go untouched before
+	// StopIdle stops it; zero disables it
+	idleStop time.Duration
+	// autosaveMu is held while an autosave writes, so saves never overlap
+	autosaveMu   sync.Mutex
+	autosaveKeep int
+	lastAutoSave time.Time
+	autosaveErr  error
+	// onFinish holds the callbacks registered with OnFinish
+	onFinish []func(c *Chronometer)
+	// onStateChange holds the callbacks registered with OnStateChange
+	onStateChange []func(id int, event string, at time.Time)
+	// onAutoSave holds the callbacks registered with OnAutoSave
+	onAutoSave []func(err error)
+}
+
+func NewChronoManager(count int) *ChronoManager {
+	cm := &ChronoManager{
+		chronometers: make([]*Chronometer, count),
+		sessionStart: time.Now(),
+		indicators:   "color",
+		maxTimers:    DefaultMaxTimers,
+		location:     time.Local,
+		exportDigits: 3,
+		exclusive:    true,
+		nextID:       count + 1,
+	}
+	for i := 0; i < count; i++ {
+		cm.chronometers[i] = NewChronometer(i + 1)
+	}
+	return cm
+}
+
+// checkTimerCount reports a descriptive error when count exceeds max
+func checkTimerCount(count, max int) error {
+	if count > max {
+		return fmt.Errorf("too many timers: %d requested, the maximum is %d", count, max)
+	}
+	return nil
+}
+
+// SetExportPrecision sets the fractional digits used for elapsed times in
+// CSV exports and Markdown summaries, by precision name
+func (cm *ChronoManager) SetExportPrecision(name string) error {
+	digits, ok := precisionDigits[name]
+	if !ok {
+		return fmt.Errorf("unknown precision %q", name)
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.exportDigits = digits
+	return nil
+}
+
+// formatTimestamp formats t for an export in the manager's time zone, empty
+// for the zero time
+func (cm *ChronoManager) formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(cm.location).Format(time.RFC3339)
+}
+
+// formatExport formats d for an export, rounded to the export precision
+func (cm *ChronoManager) formatExport(d time.Duration) string {
+	unit := time.Second
+	for i := 0; i < cm.exportDigits; i++ {
+		unit /= 10
+	}
+	return formatDurationPrecision(d.Round(unit), cm.exportDigits)
+}
+
+// SetTimeZone sets the zone timestamps are written in, by IANA name such as
+// "Europe/Berlin", or "UTC" or "Local"
+func (cm *ChronoManager) SetTimeZone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.location = loc
+	return nil
+}
+
+// expandHome replaces a leading ~ in path with the user's home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// SetSaveDir sets the directory relative file names are resolved in by the
+// methods that save, load or export, after expanding a leading ~. The
+// directory must exist. An empty dir uses the working directory.
+func (cm *ChronoManager) SetSaveDir(dir string) error {
+	if dir != "" {
+		expanded, err := expandHome(dir)
+		if err != nil {
+			return err
+		}
+		// Made absolute so that resolving a name twice changes nothing
+		if dir, err = filepath.Abs(expanded); err != nil {
+			return err
+		}
+		info, err := os.Stat(dir)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("save directory %s does not exist", dir)
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("save directory %s is not a directory", dir)
+		}
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.saveDir = dir
+	return nil
+}
+
+// SaveDir returns the directory relative file names are resolved in, "."
+// for the working directory
+func (cm *ChronoManager) SaveDir() string {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.saveDir == "" {
+		return "."
+	}
+	return cm.saveDir
+}
+
+// resolvePath expands a leading ~ in filename and places a relative name in
+// the save directory. Absolute names are used as they are.
+func (cm *ChronoManager) resolvePath(filename string) string {
+	if expanded, err := expandHome(filename); err == nil {
+		filename = expanded
+	}
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.saveDir == "" {
+		return filename
+	}
+	return filepath.Join(cm.saveDir, filename)
+}
+
+// inZone returns cd with all of its timestamps expressed in loc
+func inZone(cd ChronoData, loc *time.Location) ChronoData {
+	for i, seg := range cd.Segments {
+		cd.Segments[i].Start = seg.Start.In(loc)
+		if !seg.End.IsZero() {
+			cd.Segments[i].End = seg.End.In(loc)
+		}
+	}
+	for i, note := range cd.Notes {
+		cd.Notes[i].Time = note.Time.In(loc)
+	}
+	for _, t := range []*time.Time{&cd.ScheduledAt, &cd.StartedAt, &cd.StoppedAt} {
+		if !t.IsZero() {
+			*t = t.In(loc)
+		}
+	}
+	return cd
+}
+
+// SetMaxTimers sets the maximum number of chronometers AddChronometer allows
+func (cm *ChronoManager) SetMaxTimers(max int) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.maxTimers = max
+}
+
+// AddChronometer appends a new chronometer with the next sequential ID and
+// returns a copy of it. IDs are never reused, even after the timer holding
+// the highest one is removed. It returns an error once the maximum number
+// of timers has been reached.
+func (cm *ChronoManager) AddChronometer() (*Chronometer, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if err := checkTimerCount(len(cm.chronometers)+1, cm.maxTimers); err != nil {
+		return nil, err
+	}
+
+	c := NewChronometer(cm.nextID)
+	cm.nextID++
+	c.displayLabel = cm.uniqueLabelLocked(c.displayLabel, c)
+	cm.chronometers = append(cm.chronometers, c)
+	return c.clone(), nil
+}
+
+// RemoveChronometer stops and removes the chronometer with the given
+// 1-based ID, the number in its title. The chronometers after it move up
+// one place but keep their IDs.
+func (cm *ChronoManager) RemoveChronometer(id int) error {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	index := cm.indexLocked(id)
+	if index < 0 {
+		return fmt.Errorf("%w: %d", ErrTimerNotFound, id)
+	}
+
+	cm.stopLocked(index)
+	cm.chronometers = append(cm.chronometers[:index], cm.chronometers[index+1:]...)
+	if cm.current >= index && cm.current > 0 {
+		cm.current--
+	}
+	return nil
+}
+
+// SetAllowDuplicateLabels controls whether several chronometers may share a label
+func (cm *ChronoManager) SetAllowDuplicateLabels(allow bool) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.allowDup = allow
+}
+
+// SetUniqueLabels makes RenameChronometer reject a label that another
+// chronometer already uses, instead of numbering it
+func (cm *ChronoManager) SetUniqueLabels(unique bool) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.uniqueLabels = unique
+}
+
+// uniqueLabelLocked returns label, or label with a " (n)" suffix if another
+// chronometer than self already uses it. Empty labels and managers allowing
+// duplicates get label back unchanged. The caller must hold cm.mutex.
+func (cm *ChronoManager) uniqueLabelLocked(label string, self *Chronometer) string {
+	if cm.allowDup || label == "" {
+		return label
+	}
+
+	taken := func(l string) bool {
+		for _, c := range cm.chronometers {
+			if c != self && c.displayLabel == l {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidate := label
+	for n := 2; taken(candidate); n++ {
+		candidate = fmt.Sprintf("%s (%d)", label, n)
+	}
+	return candidate
+}
+
+// ResetLabels restores every chronometer's default label, leaving all of
+// its timing state alone
+func (cm *ChronoManager) ResetLabels() {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	for _, c := range cm.chronometers {
+		c.displayLabel = defaultLabel(c.id)
+	}
+}
+
+// RenameChronometer sets the trimmed label of the chronometer and returns the
+// label actually applied. A label in use by another chronometer is numbered,
+// or rejected with SetUniqueLabels. On error the old label is kept.
+func (cm *ChronoManager) RenameChronometer(index int, label string) (string, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return "", fmt.Errorf("%w: index %d", ErrTimerNotFound, index)
+	}
+
+	c := cm.chronometers[index]
+	label = strings.TrimSpace(label)
+	if cm.uniqueLabels && !cm.allowDup {
+		for _, other := range cm.chronometers {
+			if other != c && other.displayLabel == label {
+				return "", fmt.Errorf("label %q is already used by timer %d", label, other.id)
+			}
+		}
+	}
+	if err := c.SetLabel(cm.uniqueLabelLocked(label, c)); err != nil {
+		return "", err
+	}
+	return c.displayLabel, nil
+}
+
+// SetElapsed sets the counted time of the chronometer under the manager lock
+func (cm *ChronoManager) SetElapsed(index int, d time.Duration) error {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return fmt.Errorf("%w: index %d", ErrTimerNotFound, index)
+	}
+	cm.chronometers[index].SetElapsed(d)
+	return nil
+}
+
+// MergeTimers adds the elapsed time and laps of the source chronometer to
+// the destination and resets the source, for a task timed on two timers by
+// mistake. A running source is stopped first; the destination keeps running
+// or not as it was. The source's reset cannot be undone, as that would count
+// its time twice. A merge that would take the destination past its maximum
+// duration is refused rather than losing the excess.
+func (cm *ChronoManager) MergeTimers(srcIndex, dstIndex int) error {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	for _, index := range []int{srcIndex, dstIndex} {
+		if index < 0 || index >= len(cm.chronometers) {
+			return fmt.Errorf("%w: index %d", ErrTimerNotFound, index)
+		}
+	}
+	src, dst := cm.chronometers[srcIndex], cm.chronometers[dstIndex]
+	if srcIndex == dstIndex {
+		return fmt.Errorf("cannot merge timer %d into itself", src.id)
+	}
+
+	if total := dst.elapsed() + src.elapsed(); dst.maxDuration > 0 && total > dst.maxDuration {
+		return fmt.Errorf("timer %d would reach %s, past its maximum of %s", dst.id, formatDuration(total), formatDuration(dst.maxDuration))
+	}
+
+	cm.stopLocked(srcIndex)
+	dst.SetElapsed(dst.elapsed() + src.elapsed())
+	dst.laps = append(dst.laps, src.laps...)
+	dst.splits = append(dst.splits, src.splits...)
+	src.Reset()
+	src.undoable = false
+	if cm.resetStarts {
+		src.startCount = 0
+	}
+	return nil
+}
+
+// SetCategory sets the category of the chronometer under the manager lock
+func (cm *ChronoManager) SetCategory(index int, category string) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return fmt.Errorf("%w: index %d", ErrTimerNotFound, index)
+	}
+	cm.chronometers[index].SetCategory(category)
+	return nil
+}
+
+// TotalByCategory sums the time counted by the chronometers of each
+// category. Chronometers without one are summed under "".
+func (cm *ChronoManager) TotalByCategory() map[string]time.Duration {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	totals := make(map[string]time.Duration)
+	for _, c := range cm.chronometers {
+		totals[c.category] += c.elapsed()
+	}
+	return totals
+}
+
+// SetExclusiveMode chooses whether starting a chronometer stops every other
+// one (the default) or leaves them running alongside it
+func (cm *ChronoManager) SetExclusiveMode(exclusive bool) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.exclusive = exclusive
+}
+
+// ExclusiveMode reports whether starting a chronometer stops the others
+func (cm *ChronoManager) ExclusiveMode() bool {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return cm.exclusive
+}
+
+// SetCurrent records the index of the chronometer keyboard shortcuts act
+// on, normally the one last focused. Out of range indexes are ignored.
+func (cm *ChronoManager) SetCurrent(index int) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index >= 0 && index < len(cm.chronometers) {
+		cm.current = index
+	}
+}
+
+// Current returns the index of the chronometer keyboard shortcuts act on
+func (cm *ChronoManager) Current() int {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return cm.current
+}
+
+// SortKeys lists the orders SortBy accepts, in the order the UI cycles them
+var SortKeys = []string{"id", "elapsed", "label"}
+
+// SortBy returns the indexes of the chronometers ordered by "id", "elapsed"
+// (longest first) or "label" (case-insensitive), for display. The sort is
+// stable. The chronometers themselves stay where they are, so the indexes
+// taken by the other methods keep pointing at the same timers.
+func (cm *ChronoManager) SortBy(key string) ([]int, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	var less func(a, b *Chronometer) bool
+	switch key {
+	case "id":
+		less = func(a, b *Chronometer) bool { return a.id < b.id }
+	case "elapsed":
+		// Read running timers once so they compare at the same instant
+		elapsed := make(map[*Chronometer]time.Duration, len(cm.chronometers))
+		for _, c := range cm.chronometers {
+			elapsed[c] = c.elapsed()
+		}
+		less = func(a, b *Chronometer) bool { return elapsed[a] > elapsed[b] }
+	case "label":
+		less = func(a, b *Chronometer) bool {
+			return strings.ToLower(a.displayLabel) < strings.ToLower(b.displayLabel)
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort key %q: want id, elapsed or label", key)
+	}
+
+	order := make([]int, len(cm.chronometers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return less(cm.chronometers[order[i]], cm.chronometers[order[j]])
+	})
+	return order, nil
+}
+
+// StartChronometer starts the chronometer and, in exclusive mode, stops
+// every other one. Starting a chronometer that is already running leaves
+// it untouched, so repeated requests don't open new segments or count extra
+// starts.
+func (cm *ChronoManager) StartChronometer(index int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	cm.startLocked(index)
+}
+
+// startLocked starts the chronometer at index, stopping the others in
+// exclusive mode. The manager lock must be held.
+func (cm *ChronoManager) startLocked(index int) {
+	cm.stopOthersLocked(index)
+
+	// Start the selected chronometer
+	if index >= 0 && index < len(cm.chronometers) {
+		cm.chronometers[index].Start()
+	}
+}
+
+// stopOthersLocked stops all running chronometers but index in exclusive mode
+func (cm *ChronoManager) stopOthersLocked(index int) {
+	for i, c := range cm.chronometers {
+		if cm.exclusive && i != index && c.isRunning {
+			cm.stopLocked(i)
+		}
+	}
+}
+
+// stopLocked stops the chronometer at index, if there is one. The
+// manager lock must be held.
+func (cm *ChronoManager) stopLocked(index int) {
+	if index >= 0 && index < len(cm.chronometers) {
+		cm.chronometers[index].Stop()
+	}
+}
+
+// PauseChronometer pauses the chronometer under the manager lock
+func (cm *ChronoManager) PauseChronometer(index int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index >= 0 && index < len(cm.chronometers) {
+		cm.chronometers[index].Pause()
+	}
+}
+
+// ResumeChronometer resumes a paused chronometer, stopping the others in
+// exclusive mode as StartChronometer does
+func (cm *ChronoManager) ResumeChronometer(index int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index >= 0 && index < len(cm.chronometers) && cm.chronometers[index].paused {
+		cm.stopOthersLocked(index)
+		cm.chronometers[index].Resume()
+	}
+}
+
+// ScheduleStart arms the chronometer to start by itself at the given time,
+// as StartChronometer would. A zero time cancels the schedule, as do
+// starting or resetting the chronometer.
+func (cm *ChronoManager) ScheduleStart(index int, at time.Time) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index >= 0 && index < len(cm.chronometers) {
+		cm.chronometers[index].scheduledAt = at
+	}
+}
+
+// OnFinish registers f to be called for every countdown that runs out,
+// once per countdown, with a copy of the chronometer taken after it
+// stopped. Callbacks run on the goroutine calling StopFinished, after the
+// manager lock is released, so they may call back into the manager.
+func (cm *ChronoManager) OnFinish(f func(c *Chronometer)) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.onFinish = append(cm.onFinish, f)
+}
+
+// OnStateChange registers f to be called whenever a chronometer starts,
+// stops or is reset, with its ID, one of EventStart, EventStop or
+// EventReset and the time of the transition. Callbacks run after the
+// manager lock is released, so they may call back into the manager.
+func (cm *ChronoManager) OnStateChange(f func(id int, event string, at time.Time)) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.onStateChange = append(cm.onStateChange, f)
+}
+
+// unlockNotify releases the manager lock, then reports the transitions the
+// chronometers went through while it was held to the OnStateChange
+// callbacks, in the order they happened
+func (cm *ChronoManager) unlockNotify() {
+	type change struct {
+		id int
+		stateChange
+	}
+	var changes []change
+	for _, c := range cm.chronometers {
+		for _, sc := range c.changes {
+			changes = append(changes, change{c.id, sc})
+		}
+		c.changes = nil
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].at.Before(changes[j].at)
+	})
+	callbacks := cm.onStateChange
+	cm.mutex.Unlock()
+
+	for _, ch := range changes {
+		for _, f := range callbacks {
+			f(ch.id, ch.event, ch.at)
+		}
+	}
+}
+
+// StopFinished stops the countdowns that have reached zero and the
+// chronometers that have reached their maximum duration, calls the
+// OnFinish callbacks for them and returns their IDs. Each finished
+// countdown is reported once. Pomodoro timers are not stopped but go on
+// into their next phase from the moment the last one ended; a finished
+// work phase counts as a pomodoro.
+func (cm *ChronoManager) StopFinished(now time.Time) []int {
+	cm.mutex.Lock()
+	var finished []int
+	var copies []Chronometer
+	for i, c := range cm.chronometers {
+		if c.expire(now) {
+			finished = append(finished, i)
+			if c.pomodoroPhase != "" && c.elapsedTime >= c.target {
+				if c.pomodoroPhase == PomodoroWork {
+					c.pomodoros++
+				}
+				end := c.startTime.Add(c.target)
+				c.setPomodoroPhase(c.nextPomodoroPhase(), end)
+				c.beginRun(end)
+				c.changes = append(c.changes, stateChange{EventStart, end})
+			}
+			copies = append(copies, *c)
+		}
+	}
+	callbacks := cm.onFinish
+	cm.unlockNotify()
+
+	for i := range copies {
+		for _, f := range callbacks {
+			f(&copies[i])
+		}
+	}
+	return finished
+}
+
+// StartPomodoro turns the chronometer into a Pomodoro timer and starts a
+// work phase from zero, stopping the others in exclusive mode. Completed
+// pomodoros are kept.
+func (cm *ChronoManager) StartPomodoro(index int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index >= 0 && index < len(cm.chronometers) {
+		cm.stopLocked(index)
+		cm.chronometers[index].setPomodoroPhase(PomodoroWork, time.Now())
+		cm.startLocked(index)
+	}
+}
+
+// SkipPomodoroPhase moves a Pomodoro timer straight on to its next phase
+// and runs it. A skipped work phase does not count as a pomodoro.
+func (cm *ChronoManager) SkipPomodoroPhase(index int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index >= 0 && index < len(cm.chronometers) && cm.chronometers[index].pomodoroPhase != "" {
+		c := cm.chronometers[index]
+		now := time.Now()
+		if c.isRunning {
+			// As when a phase runs out, one run ends and the next begins
+			c.changes = append(c.changes, stateChange{EventStop, now}, stateChange{EventStart, now})
+		}
+		c.setPomodoroPhase(c.nextPomodoroPhase(), now)
+		cm.startLocked(index)
+	}
+}
+
+// StartDue starts every chronometer whose scheduled start is not after now
+// and returns their IDs
+func (cm *ChronoManager) StartDue(now time.Time) []int {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	var started []int
+	for i, c := range cm.chronometers {
+		if !c.scheduledAt.IsZero() && !c.scheduledAt.After(now) {
+			cm.startLocked(i)
+			started = append(started, i)
+		}
+	}
+	return started
+}
+
+// StopChronometer stops the chronometer under the manager lock
+func (cm *ChronoManager) StopChronometer(index int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	cm.stopLocked(index)
+}
+
+// ResetChronometer resets the chronometer under the manager lock. Its start
+// count is cleared too if the manager is set to do so.
+func (cm *ChronoManager) ResetChronometer(index int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index >= 0 && index < len(cm.chronometers) {
+		cm.chronometers[index].Reset()
+		if cm.resetStarts {
+			cm.chronometers[index].startCount = 0
+		}
+	}
+}
+
+// UndoReset undoes the last reset of the chronometer, stopping the others
+// in exclusive mode if that runs it again. It reports whether there was a
+// reset to undo.
+func (cm *ChronoManager) UndoReset(index int) bool {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return false
+	}
+	c := cm.chronometers[index]
+	if !c.undoable {
+		return false
+	}
+	if c.lastRunning {
+		cm.stopOthersLocked(index)
+	}
+	return c.UndoReset()
+}
+
+// RestartChronometer resets the chronometer and runs it from zero, stopping
+// every other one in exclusive mode as StartChronometer does
+func (cm *ChronoManager) RestartChronometer(index int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index >= 0 && index < len(cm.chronometers) {
+		c := cm.chronometers[index]
+		if cm.resetStarts {
+			c.startCount = 0
+		}
+		cm.stopOthersLocked(index)
+		c.Restart()
+	}
+}
+
+// StartAll starts every chronometer that isn't running yet and reports
+// whether it did. In exclusive mode only one timer may run, so it does
+// nothing and returns false.
+func (cm *ChronoManager) StartAll() bool {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if cm.exclusive {
+		return false
+	}
+	for i := range cm.chronometers {
+		cm.startLocked(i)
+	}
+	return true
+}
+
+// StopAll stops every running chronometer
+func (cm *ChronoManager) StopAll() {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	for i, c := range cm.chronometers {
+		if c.isRunning {
+			cm.stopLocked(i)
+		}
+	}
+}
+
+// ResetAll resets every chronometer; running ones keep running from zero
+func (cm *ChronoManager) ResetAll() {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	for _, c := range cm.chronometers {
+		c.Reset()
+		if cm.resetStarts {
+			c.startCount = 0
+		}
+	}
+}
+
+// BulkSet applies fn to each of the given chronometers under a single lock,
+// skipping IDs that are out of range
+func (cm *ChronoManager) BulkSet(ids []int, fn func(*Chronometer)) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	for _, id := range ids {
+		if id >= 0 && id < len(cm.chronometers) {
+			fn(cm.chronometers[id])
+		}
+	}
+}
+
+// SelectedIDs returns the IDs of the selected chronometers
+func (cm *ChronoManager) SelectedIDs() []int {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	var ids []int
+	for i, c := range cm.chronometers {
+		if c.selected {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// PauseAll pauses every running chronometer and returns their IDs
+func (cm *ChronoManager) PauseAll() []int {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	var ids []int
+	for i, c := range cm.chronometers {
+		if c.isRunning {
+			c.Pause()
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// Resume restarts chronometers paused by PauseAll, unless they were stopped
+// or started since. Resuming does not count as another start.
+func (cm *ChronoManager) Resume(ids []int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	for _, id := range ids {
+		if id >= 0 && id < len(cm.chronometers) {
+			cm.chronometers[id].Resume()
+		}
+	}
+}
+
+// SetResetClearsStartCount controls whether ResetChronometer also zeroes
+// the start count
+func (cm *ChronoManager) SetResetClearsStartCount(clear bool) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.resetStarts = clear
+}
+
+// SetIdleStop sets how long a running chronometer may go without an
+// interaction before StopIdle stops it. Zero disables idle stops.
+func (cm *ChronoManager) SetIdleStop(d time.Duration) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.idleStop = d
+}
+
+// Touch records an interaction with the chronometer, such as a button
+// press, holding off its idle stop
+func (cm *ChronoManager) Touch(index int) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index >= 0 && index < len(cm.chronometers) {
+		cm.chronometers[index].lastInteraction = time.Now()
+	}
+}
+
+// StopIdle stops every running chronometer that has gone without an
+// interaction for the idle stop time, flags it as stopped for being idle,
+// and returns their IDs. A chronometer running with no interaction on
+// record, e.g. one restored from a save, gets the full idle time from now.
+func (cm *ChronoManager) StopIdle(now time.Time) []int {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if cm.idleStop <= 0 {
+		return nil
+	}
+	var stopped []int
+	for i, c := range cm.chronometers {
+		if !c.isRunning {
+			continue
+		}
+		if c.lastInteraction.IsZero() {
+			c.lastInteraction = now
+			continue
+		}
+		if now.Sub(c.lastInteraction) >= cm.idleStop {
+			cm.stopLocked(i)
+			c.idleStopped = true
+			stopped = append(stopped, i)
+		}
+	}
+	return stopped
+}
+
+// StartCount returns how many times the chronometer has been started
+func (cm *ChronoManager) StartCount(index int) int {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return 0
+	}
+	return cm.chronometers[index].startCount
+}
+
+// Chronometers returns copies of the chronometers in display order, taken
+// under the lock. Their getters are safe to call while other goroutines
+// change the timers, and changing them doesn't affect the manager.
+func (cm *ChronoManager) Chronometers() []*Chronometer {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	chronometers := make([]*Chronometer, len(cm.chronometers))
+	for i, c := range cm.chronometers {
+		chronometers[i] = c.clone()
+	}
+	return chronometers
+}
+
+// Count returns the number of chronometers
+func (cm *ChronoManager) Count() int {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return len(cm.chronometers)
+}
+
+// Get returns a copy of the chronometer whose ID() is id, taken under the
+// lock as for Chronometers. It reports false if there is no such timer.
+func (cm *ChronoManager) Get(id int) (*Chronometer, bool) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	index := cm.indexLocked(id)
+	if index < 0 {
+		return nil, false
+	}
+	return cm.chronometers[index].clone(), true
+}
+
+// IndexOf returns the current index of the chronometer with the given
+// 1-based ID, the number in its title, which stays with the timer when
+// others are removed
+func (cm *ChronoManager) IndexOf(id int) (int, bool) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	i := cm.indexLocked(id)
+	return i, i >= 0
+}
+
+// indexLocked is IndexOf for callers holding the lock, returning -1 for an
+// unknown ID
+func (cm *ChronoManager) indexLocked(id int) int {
+	for i, c := range cm.chronometers {
+		if c.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyOf returns a copy of the chronometer at index taken under the lock,
+// for the UI to read while other goroutines change the original
+func (cm *ChronoManager) copyOf(index int) (Chronometer, bool) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return Chronometer{}, false
+	}
+	return *cm.chronometers[index], true
+}
+
+// ToggleChronometer stops the chronometer if it is running and starts it
+// otherwise, as StartChronometer would
+func (cm *ChronoManager) ToggleChronometer(index int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return
+	}
+	if cm.chronometers[index].isRunning {
+		cm.stopLocked(index)
+	} else {
+		cm.startLocked(index)
+	}
+}
+
+// Find returns snapshots of the chronometers matching pred, or of all of
+// them if pred is nil, in display order. The snapshots are taken under the
+// lock and pred runs after it is released, so pred may call back into the
+// manager.
+func (cm *ChronoManager) Find(pred func(ChronoData) bool) []ChronoData {
+	_, matches := cm.find(pred)
+	return matches
+}
+
+// find is Find also returning the index of each match
+func (cm *ChronoManager) find(pred func(ChronoData) bool) ([]int, []ChronoData) {
+	var indexes []int
+	var matches []ChronoData
+	for i, cd := range cm.snapshots() {
+		if pred == nil || pred(cd) {
+			indexes = append(indexes, i)
+			matches = append(matches, cd)
+		}
+	}
+	return indexes, matches
+}
+
+// snapshots returns a snapshot of every chronometer taken under the lock
+func (cm *ChronoManager) snapshots() []ChronoData {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	all := make([]ChronoData, len(cm.chronometers))
+	for i, c := range cm.chronometers {
+		all[i] = c.snapshot()
+	}
+	return all
+}
+
+// FindByLabel returns the chronometers whose label contains substr,
+// ignoring case
+func (cm *ChronoManager) FindByLabel(substr string) []ChronoData {
+	return cm.Find(labelContains(substr))
+}
+
+// Filter returns the indexes of the chronometers whose label contains
+// substr, ignoring case, in display order. An empty substr matches all.
+func (cm *ChronoManager) Filter(substr string) []int {
+	indexes, _ := cm.find(labelContains(substr))
+	return indexes
+}
+
+// labelContains matches chronometers whose label contains substr, ignoring
+// case
+func labelContains(substr string) func(ChronoData) bool {
+	substr = strings.ToLower(substr)
+	return func(cd ChronoData) bool {
+		return strings.Contains(strings.ToLower(cd.DisplayLabel), substr)
+	}
+}
+
+// FindRunning returns the chronometers that are currently running
+func (cm *ChronoManager) FindRunning() []ChronoData {
+	return cm.Find(func(cd ChronoData) bool {
+		return cd.IsRunning
+	})
+}
+
+// GoalMet reports whether the chronometer has reached its daily goal today
+func (cm *ChronoManager) GoalMet(index int) bool {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return false
+	}
+	return cm.chronometers[index].goalMet(time.Now())
+}
+
+// RunningCount returns how many chronometers are running
+func (cm *ChronoManager) RunningCount() int {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	count := 0
+	for _, c := range cm.chronometers {
+		if c.isRunning {
+			count++
+		}
+	}
+	return count
+}
+
+// TotalElapsed returns the time recorded by all chronometers together,
+// including the live time of running ones
+func (cm *ChronoManager) TotalElapsed() time.Duration {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	var total time.Duration
+	for _, c := range cm.chronometers {
+		total += c.elapsed()
+	}
+	return total
+}
+
+// Shares returns each chronometer's elapsed time as a fraction of
+// TotalElapsed, keyed by the index StartChronometer and friends take. All
+// shares are 0 while nothing has been recorded.
+func (cm *ChronoManager) Shares() map[int]float64 {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	elapsed := make([]time.Duration, len(cm.chronometers))
+	var total time.Duration
+	for i, c := range cm.chronometers {
+		elapsed[i] = c.elapsed()
+		total += elapsed[i]
+	}
+
+	shares := make(map[int]float64, len(elapsed))
+	for i, d := range elapsed {
+		if total > 0 {
+			shares[i] = float64(d) / float64(total)
+		} else {
+			shares[i] = 0
+		}
+	}
+	return shares
+}
+
+// Leaderboard returns the stopped chronometers that have recorded time,
+// fastest first
+func (cm *ChronoManager) Leaderboard() []ChronoData {
+	return cm.leaderboard(false)
+}
+
+// LiveLeaderboard is Leaderboard with running chronometers ranked by their
+// current elapsed time
+func (cm *ChronoManager) LiveLeaderboard() []ChronoData {
+	return cm.leaderboard(true)
+}
+
+func (cm *ChronoManager) leaderboard(includeRunning bool) []ChronoData {
+	entries := cm.Find(func(cd ChronoData) bool {
+		return cd.ElapsedTime > 0 && (includeRunning || !cd.IsRunning)
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ElapsedTime < entries[j].ElapsedTime
+	})
+	return entries
+}
+
+// AddNote appends a note stamped with the current time to the chronometer
+func (cm *ChronoManager) AddNote(index int, text string) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index >= 0 && index < len(cm.chronometers) {
+		c := cm.chronometers[index]
+		c.notes = append(c.notes, Note{Time: time.Now(), Text: text})
+	}
+}
+
+// Overtime returns how far the chronometer has run past its overtime mark,
+// or zero if it hasn't reached it or has none
+func (cm *ChronoManager) Overtime(index int) time.Duration {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return 0
+	}
+	return cm.chronometers[index].overtime()
+}
+
+// LapChronometer records a lap on the chronometer under the manager lock
+func (cm *ChronoManager) LapChronometer(index int) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index >= 0 && index < len(cm.chronometers) {
+		cm.chronometers[index].Lap()
+	}
+}
+
+// Tap records a beat for the tap tempo. The first tap resets and starts
+// the chronometer; every later one records the interval as a lap.
+func (cm *ChronoManager) Tap(index int) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return
+	}
+	c := cm.chronometers[index]
+	if c.tapping && c.isRunning {
+		c.Lap()
+		return
+	}
+	c.Reset()
+	cm.startLocked(index)
+	c.tapping = true
+}
+
+// Tempo returns the beats per minute implied by the average of the last
+// window lap intervals, or of all of them if window is not positive. It is
+// 0 until an interval has been recorded.
+func (cm *ChronoManager) Tempo(index int, window int) float64 {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return 0
+	}
+	laps := cm.chronometers[index].laps
+	if window > 0 && len(laps) > window {
+		laps = laps[len(laps)-window:]
+	}
+	var total time.Duration
+	for _, lap := range laps {
+		total += lap
+	}
+	if total <= 0 {
+		return 0
+	}
+	return float64(len(laps)) * float64(time.Minute) / float64(total)
+}
+
+// Utilization returns the share of wall-clock time since the chronometer
+// was first started (or last reset) that it spent running. A timer that has
+// run without interruption reports 1.
+func (cm *ChronoManager) Utilization(index int) float64 {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return 0
+	}
+	return cm.chronometers[index].utilization(time.Now())
+}
+
+// ElapsedDays returns the number of complete 24-hour days the chronometer has run
+func (cm *ChronoManager) ElapsedDays(index int) int {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return 0
+	}
+	return int(cm.chronometers[index].GetElapsedTime() / (24 * time.Hour))
+}
+
+// RemainingBudget returns how much of the budget is left for the chronometer.
+// The result is negative once the elapsed time has gone over budget.
+func (cm *ChronoManager) RemainingBudget(index int) time.Duration {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if index < 0 || index >= len(cm.chronometers) {
+		return 0
+	}
+	c := cm.chronometers[index]
+	return c.budget - c.GetElapsedTime()
+}
+
+// exportFilterNames lists the export filters in the order the UI cycles through them.
+var exportFilterNames = []string{"all", "running", "selected"}
+
+// exportFilters maps an export filter name to the predicate deciding which
+// chronometers are included in saves and exports.
+var exportFilters = map[string]func(*Chronometer) bool{
+	"all":      func(c *Chronometer) bool { return true },
+	"running":  func(c *Chronometer) bool { return c.isRunning },
+	"selected": func(c *Chronometer) bool { return c.selected },
+}
+
+// AdjustTarget moves the countdown target of the chronometer by delta
+func (cm *ChronoManager) AdjustTarget(index int, delta time.Duration) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	if index >= 0 && index < len(cm.chronometers) {
+		cm.chronometers[index].AdjustTarget(delta)
+	}
+}
+
+func (cm *ChronoManager) SaveToFile(filename string) error {
+	return cm.SaveToFileFiltered(filename, nil)
+}
+
+// SaveToFileFiltered saves only the chronometers for which include returns
+// true. A nil include saves all of them.
+func (cm *ChronoManager) SaveToFileFiltered(filename string, include func(*Chronometer) bool) error {
+	filename = cm.resolvePath(filename)
+	jsonData, err := json.MarshalIndent(cm.buildSaveData(include), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, jsonData)
+}
+
+// writeFileAtomic writes data to a temporary file next to filename and
+// renames it into place, so a crash mid-write never leaves a truncated
+// save behind. The temporary file is removed if anything fails.
+func writeFileAtomic(filename string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// buildSaveData collects the state of the chronometers for which include
+// returns true, or of all of them if include is nil, under the lock
+func (cm *ChronoManager) buildSaveData(include func(*Chronometer) bool) SaveData {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	data := SaveData{
+		Chronometers: []ChronoData{},
+		SaveTime:     time.Now().In(cm.location),
+		Indicators:   cm.indicators,
+		TimeZone:     cm.location.String(),
+	}
+	if cm.current < len(cm.chronometers) {
+		data.SelectedID = cm.chronometers[cm.current].id
+	}
+
+	for _, c := range cm.chronometers {
+		if include != nil && !include(c) {
+			continue
+		}
+		data.Chronometers = append(data.Chronometers, inZone(c.snapshot(), cm.location))
+		data.TotalElapsed += c.elapsed()
+	}
+
+	return data
+}
+
+// LoadFromFile loads chronometers saved by SaveToFile. With resumeRunning,
+// timers that were running when saved are credited with the time since the
+// save, as if they had kept running; otherwise they resume from the value
+// saved. A file that isn't a valid save is reported as a *ParseError.
+func (cm *ChronoManager) LoadFromFile(filename string, resumeRunning bool) error {
+	filename = cm.resolvePath(filename)
+	data, err := readSaveFile(filename)
+	if err != nil {
+		return err
+	}
+
+	cm.applySaveData(data, resumeRunning)
+	return nil
+}
+
+// backupName returns the name of the nth older copy of filename, e.g.
+// autosave.2.json for autosave.json. The newest file is n = 0.
+func backupName(filename string, n int) string {
+	if n == 0 {
+		return filename
+	}
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(filename, ext), n, ext)
+}
+
+// rotateBackups shifts filename and its backups one place older, keeping
+// at most keep backups. Missing files are skipped.
+func rotateBackups(filename string, keep int) error {
+	for n := keep; n > 0; n-- {
+		err := os.Rename(backupName(filename, n-1), backupName(filename, n))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveRotated saves to filename after moving the previous save and its
+// backups one place older, keeping at most keep backups
+func (cm *ChronoManager) SaveRotated(filename string, keep int) error {
+	filename = cm.resolvePath(filename)
+	if err := rotateBackups(filename, keep); err != nil {
+		return err
+	}
+	return cm.SaveToFile(filename)
+}
+
+// LoadNewestBackup loads filename or, if it can't be read, the newest of
+// its keep backups that can. It returns the name of the file loaded. If
+// none loads, the error is the newest one other than a missing file, so a
+// not-exist error means nothing has been saved yet.
+func (cm *ChronoManager) LoadNewestBackup(filename string, keep int, resumeRunning bool) (string, error) {
+	filename = cm.resolvePath(filename)
+
+	var loadErr error
+	for n := 0; n <= keep; n++ {
+		name := backupName(filename, n)
+		err := cm.LoadFromFile(name, resumeRunning)
+		if err == nil {
+			return name, nil
+		}
+		if loadErr == nil || os.IsNotExist(loadErr) {
+			loadErr = err
+		}
+	}
+	return "", loadErr
+}
+
+// SetAutoSaveBackups sets how many older autosaves StartAutoSave keeps as
+// numbered backups, as SaveRotated does. The default of 0 keeps none.
+func (cm *ChronoManager) SetAutoSaveBackups(keep int) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.autosaveKeep = keep
+}
+
+// StartAutoSave saves to filename every interval until the returned stop
+// function is called. A tick that comes while the previous save is still
+// writing is skipped rather than overlapping it.
+func (cm *ChronoManager) StartAutoSave(filename string, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cm.autoSave(filename)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// autoSave saves to filename unless another autosave is still writing, and
+// records the outcome for LastAutoSave
+func (cm *ChronoManager) autoSave(filename string) {
+	if !cm.autosaveMu.TryLock() {
+		return
+	}
+	defer cm.autosaveMu.Unlock()
+
+	cm.mutex.Lock()
+	keep := cm.autosaveKeep
+	cm.mutex.Unlock()
+
+	err := cm.SaveRotated(filename, keep)
+
+	cm.mutex.Lock()
+	cm.autosaveErr = err
+	if err == nil {
+		cm.lastAutoSave = time.Now()
+	}
+	callbacks := cm.onAutoSave
+	cm.mutex.Unlock()
+
+	for _, f := range callbacks {
+		f(err)
+	}
+}
+
+// OnAutoSave registers f to be called after every autosave attempt with
+// its error, nil on success. Callbacks run on the autosave goroutine after
+// the manager lock is released, so they may call back into the manager.
+func (cm *ChronoManager) OnAutoSave(f func(err error)) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.onAutoSave = append(cm.onAutoSave, f)
+}
+
+// LastAutoSave returns when the last successful autosave happened, zero if
+// none has yet, and the error of the most recent attempt
+func (cm *ChronoManager) LastAutoSave() (time.Time, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return cm.lastAutoSave, cm.autosaveErr
+}
+
+// readSaveFile reads and decodes a JSON save file. Content that isn't a
+// save file is reported as a *ParseError.
+func readSaveFile(filename string) (SaveData, error) {
+	var data SaveData
+	jsonData, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return data, err
+	}
+
+	// Reject binary saves up front rather than with a cryptic JSON error
+	if trimmed := bytes.TrimSpace(jsonData); len(trimmed) == 0 || trimmed[0] != '{' {
+		return data, &ParseError{Field: "save file", Raw: filename, Err: errors.New("not JSON")}
+	}
+
+	if err = json.Unmarshal(jsonData, &data); err != nil {
+		return data, &ParseError{Field: "save file", Raw: filename, Err: err}
+	}
+	return data, nil
+}
+
+// SaveFileInfo describes a file ListSaveFiles found. Format is "JSON",
+// "YAML" or "CSV", as in the load dialog. SaveTime is zero for CSV files,
+// which don't record it, and for saves whose timers were not all counted.
+// MoreTimers is set when the file holds more than the Timers counted.
+type SaveFileInfo struct {
+	Name       string
+	Format     string
+	ModTime    time.Time
+	SaveTime   time.Time
+	Timers     int
+	MoreTimers bool
+}
+
+// maxListedTimers is how many timers ListSaveFiles counts in a file before
+// it stops reading it
+const maxListedTimers = 10000
+
+// ListSaveFiles lists the JSON, YAML and CSV saves in dir, newest first,
+// with the time each was saved and its number of timers. Files that turn
+// out not to be saves, such as JSON exports or laps CSVs, are left out.
+// Each file is read as a stream, without decoding the timers themselves.
+func ListSaveFiles(dir string) ([]SaveFileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []SaveFileInfo
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		file := SaveFileInfo{Name: entry.Name(), ModTime: info.ModTime()}
+
+		var peek func(io.Reader, *SaveFileInfo) error
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json":
+			file.Format, peek = "JSON", peekJSONSave
+		case ".yaml", ".yml":
+			file.Format, peek = "YAML", peekYAMLSave
+		case ".csv":
+			file.Format, peek = "CSV", peekCSVSave
+		default:
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		err = peek(f, &file)
+		f.Close()
+		if err != nil {
+			continue
+		}
+		files = append(files, file)
+	}
+
+	// Newest first, by the recorded save time where there is one
+	when := func(f SaveFileInfo) time.Time {
+		if f.SaveTime.IsZero() {
+			return f.ModTime
+		}
+		return f.SaveTime
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return when(files[i]).After(when(files[j]))
+	})
+	return files, nil
+}
+
+// errNotASave is returned by the peek functions for files that aren't saves
+var errNotASave = errors.New("not a save file")
+
+// peekJSONSave reads the save time and counts the timers of a JSON save,
+// skipping over each timer without keeping it
+func peekJSONSave(r io.Reader, file *SaveFileInfo) error {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return errNotASave
+	}
+
+	found := false
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "saveTime":
+			if err := dec.Decode(&file.SaveTime); err != nil {
+				return err
+			}
+		case "chronometers":
+			if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+				return errNotASave
+			}
+			found = true
+			for dec.More() {
+				if file.Timers == maxListedTimers {
+					// The save time comes after the timers
+					file.MoreTimers, file.SaveTime = true, time.Time{}
+					return nil
+				}
+				var skip struct{}
+				if err := dec.Decode(&skip); err != nil {
+					return err
+				}
+				file.Timers++
+			}
+			if _, err := dec.Token(); err != nil {
+				return err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+	if !found {
+		return errNotASave
+	}
+	return nil
+}
+
+// peekYAMLSave reads the save time and counts the timers of a YAML save as
+// SaveToYAML writes it, line by line: each timer starts with a "-" item at
+// the indentation of the first one under the top-level chronometers key
+func peekYAMLSave(r io.Reader, file *SaveFileInfo) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	found, inList, indent := false, false, -1
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		depth := len(line) - len(trimmed)
+
+		if inList && strings.HasPrefix(trimmed, "-") && (indent < 0 || depth == indent) {
+			if file.Timers == maxListedTimers {
+				file.MoreTimers, file.SaveTime = true, time.Time{}
+				return nil
+			}
+			indent = depth
+			file.Timers++
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+
+		// A top-level key ends the list of timers
+		inList = false
+		switch key, value, _ := strings.Cut(line, ":"); key {
+		case "chronometers":
+			switch strings.TrimSpace(value) {
+			case "":
+				found, inList = true, true
+			case "[]":
+				found = true
+			default:
+				return errNotASave
+			}
+		case "saveTime":
+			var header struct {
+				SaveTime time.Time `yaml:"saveTime"`
+			}
+			if err := yaml.Unmarshal([]byte(line), &header); err != nil {
+				return err
+			}
+			file.SaveTime = header.SaveTime
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !found {
+		return errNotASave
+	}
+	return nil
+}
+
+// peekCSVSave counts the timer rows of a CSV written by SaveToCSV, failing
+// for any other CSV
+func peekCSVSave(r io.Reader, file *SaveFileInfo) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	if len(header) < 3 || header[0] != "Timer ID" || header[1] != "Label" || header[2] != "Elapsed Time" {
+		return errNotASave
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(record) > 1 && !(record[0] == "" && record[1] == "Total") {
+			if file.Timers == maxListedTimers {
+				file.MoreTimers = true
+				return nil
+			}
+			file.Timers++
+		}
+	}
+}
+
+// formatDelta formats a signed duration difference, e.g. "+00:15:00.000"
+func formatDelta(d time.Duration) string {
+	if d < 0 {
+		return "-" + formatDuration(-d)
+	}
+	return "+" + formatDuration(d)
+}
+
+// TimerDiff compares one timer, matched by ID, between two saves. InA and
+// InB tell whether it is in each; Delta is ElapsedB - ElapsedA, counting a
+// missing timer as zero.
+type TimerDiff struct {
+	ID       int
+	LabelA   string
+	LabelB   string
+	ElapsedA time.Duration
+	ElapsedB time.Duration
+	Delta    time.Duration
+	InA      bool
+	InB      bool
+}
+
+// compareSaveData pairs up the chronometers of a and b by ID, in ID order,
+// including those that are the same in both
+func compareSaveData(a, b SaveData) []TimerDiff {
+	byID := make(map[int]*TimerDiff)
+	var ids []int
+	entry := func(id int) *TimerDiff {
+		if d, ok := byID[id]; ok {
+			return d
+		}
+		d := &TimerDiff{ID: id}
+		byID[id] = d
+		ids = append(ids, id)
+		return d
+	}
+	for _, cd := range a.Chronometers {
+		d := entry(cd.ID)
+		d.LabelA, d.ElapsedA, d.InA = cd.DisplayLabel, cd.ElapsedTime, true
+	}
+	for _, cd := range b.Chronometers {
+		d := entry(cd.ID)
+		d.LabelB, d.ElapsedB, d.InB = cd.DisplayLabel, cd.ElapsedTime, true
+	}
+	sort.Ints(ids)
+
+	diffs := make([]TimerDiff, 0, len(ids))
+	for _, id := range ids {
+		d := byID[id]
+		d.Delta = d.ElapsedB - d.ElapsedA
+		diffs = append(diffs, *d)
+	}
+	return diffs
+}
+
+// CompareSaves loads two JSON save files and compares their timers by ID.
+// Every timer found in either file is listed, changed or not.
+func CompareSaves(fileA, fileB string) ([]TimerDiff, error) {
+	a, err := readSaveFile(fileA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := readSaveFile(fileB)
+	if err != nil {
+		return nil, err
+	}
+	return compareSaveData(a, b), nil
+}
+
+// DiffSaves describes how the chronometers in b differ from those in a,
+// matched by ID: one line per changed, added or removed timer, in ID order.
+// Timers that are the same in both are left out.
+func DiffSaves(a, b SaveData) []string {
+	var lines []string
+	for _, d := range compareSaveData(a, b) {
+		switch {
+		case !d.InB:
+			lines = append(lines, fmt.Sprintf("Timer %d '%s': removed (was %s)", d.ID, d.LabelA, formatDuration(d.ElapsedA)))
+		case !d.InA:
+			lines = append(lines, fmt.Sprintf("Timer %d '%s': added (%s)", d.ID, d.LabelB, formatDuration(d.ElapsedB)))
+		default:
+			if d.LabelA != d.LabelB {
+				lines = append(lines, fmt.Sprintf("Timer %d: label '%s' → '%s'", d.ID, d.LabelA, d.LabelB))
+			}
+			if d.Delta != 0 {
+				lines = append(lines, fmt.Sprintf("Timer %d '%s': %s → %s (%s)", d.ID, d.LabelB,
+					formatDuration(d.ElapsedA), formatDuration(d.ElapsedB), formatDelta(d.Delta)))
+			}
+		}
+	}
+	return lines
+}
+
+// runDiff prints the differences between two save files to w
+func runDiff(w io.Writer, fileA, fileB string) error {
+	a, err := readSaveFile(fileA)
+	if err != nil {
+		return err
+	}
+	b, err := readSaveFile(fileB)
+	if err != nil {
+		return err
+	}
+
+	lines := DiffSaves(a, b)
+	if len(lines) == 0 {
+		_, err = fmt.Fprintln(w, "No differences")
+		return err
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHeadless runs the chronometers without a terminal UI, following the
+// commands read from r one per line, and writes the timers to w as a JSON
+// array once the commands run out. Timers are named by their ID:
+//
+//	start N, stop N, reset N, lap N   act on timer N
+//	sleep 1m30s                       wait, as parseHuman reads it
+//	save timers.json                  save as SaveToFile does
+//
+// Blank lines and lines starting with # are skipped. The first command that
+// fails stops the run.
+func runHeadless(manager *ChronoManager, r io.Reader, w io.Writer) error {
+	actions := map[string]func(id int){
+		"start": manager.StartChronometer,
+		"stop":  manager.StopChronometer,
+		"reset": manager.ResetChronometer,
+		"lap":   manager.LapChronometer,
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		command, arg, _ := strings.Cut(text, " ")
+		arg = strings.TrimSpace(arg)
+
+		switch command {
+		case "start", "stop", "reset", "lap":
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				return &ParseError{Line: line, Field: "timer ID", Raw: arg, Err: errors.New("want a number")}
+			}
+			index, ok := manager.IndexOf(n)
+			if !ok {
+				return fmt.Errorf("line %d: %w: %d", line, ErrTimerNotFound, n)
+			}
+			actions[command](index)
+		case "sleep":
+			d, err := parseHuman(arg)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+			time.Sleep(d)
+		case "save":
+			if arg == "" {
+				return fmt.Errorf("line %d: save needs a file name", line)
+			}
+			if err := manager.SaveToFile(arg); err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+		default:
+			return fmt.Errorf("line %d: unknown command %q", line, command)
+		}
+		manager.StopFinished(time.Now())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	manager.StopFinished(time.Now())
+	jsonData, err := json.MarshalIndent(manager.exportTimers(nil), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", jsonData)
+	return err
+}
+
+// SnapshotRecord is one line of the snapshot log: the live elapsed time of
+// every chronometer at a moment, recorded without stopping anything
+type SnapshotRecord struct {
+	Time   time.Time       `json:"time"`
+	Timers []SnapshotTimer `json:"timers"`
+}
+
+// SnapshotTimer is the state of one chronometer within a SnapshotRecord
+type SnapshotTimer struct {
+	ID      int           `json:"id"`
+	Label   string        `json:"label"`
+	Elapsed time.Duration `json:"elapsed"`
+	Running bool          `json:"running"`
+}
+
+// appendJSONLine appends v to filename as a single line of JSON, creating
+// the file if needed
+func appendJSONLine(filename string, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Snapshot appends the live elapsed time of every chronometer to a JSON
+// Lines log. Unlike a save it records no other state and stops nothing.
+func (cm *ChronoManager) Snapshot(filename string) error {
+	filename = cm.resolvePath(filename)
+
+	cm.mutex.Lock()
+	record := SnapshotRecord{
+		Time:   time.Now().In(cm.location),
+		Timers: make([]SnapshotTimer, len(cm.chronometers)),
+	}
+	for i, c := range cm.chronometers {
+		record.Timers[i] = SnapshotTimer{
+			ID:      c.id,
+			Label:   c.displayLabel,
+			Elapsed: c.GetElapsedTime(),
+			Running: c.isRunning,
+		}
+	}
+	cm.mutex.Unlock()
+
+	return appendJSONLine(filename, record)
+}
+
+// SaveToGob saves all chronometers in the compact encoding/gob format
+func (cm *ChronoManager) SaveToGob(filename string) error {
+	return cm.SaveToGobFiltered(filename, nil)
+}
+
+// SaveToGobFiltered saves only the chronometers for which include returns
+// true in the encoding/gob format. A nil include saves all of them.
+func (cm *ChronoManager) SaveToGobFiltered(filename string, include func(*Chronometer) bool) error {
+	filename = cm.resolvePath(filename)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cm.buildSaveData(include)); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, buf.Bytes())
+}
+
+// LoadFromGob loads chronometers saved by SaveToGob
+func (cm *ChronoManager) LoadFromGob(filename string) error {
+	filename = cm.resolvePath(filename)
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var data SaveData
+	if err := gob.NewDecoder(file).Decode(&data); err != nil {
+		return fmt.Errorf("%s is not a binary save file: %v", filename, err)
+	}
+
+	cm.applySaveData(data, false)
+	return nil
+}
+
+// SaveToYAML saves all chronometers as YAML, with durations written in a
+// readable form such as "1h30m0s" so the file is easy to edit by hand
+func (cm *ChronoManager) SaveToYAML(filename string) error {
+	return cm.SaveToYAMLFiltered(filename, nil)
+}
+
+// SaveToYAMLFiltered saves only the chronometers for which include returns
+// true as YAML. A nil include saves all of them.
+func (cm *ChronoManager) SaveToYAMLFiltered(filename string, include func(*Chronometer) bool) error {
+	filename = cm.resolvePath(filename)
+	yamlData, err := yaml.Marshal(cm.buildSaveData(include))
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, yamlData)
+}
+
+// LoadFromYAML loads chronometers saved by SaveToYAML or written by hand.
+// Durations may be given as strings such as "90m" or "1h30m".
+func (cm *ChronoManager) LoadFromYAML(filename string) error {
+	filename = cm.resolvePath(filename)
+	yamlData, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var data SaveData
+	if err := yaml.Unmarshal(yamlData, &data); err != nil {
+		return fmt.Errorf("%s is not a YAML save file: %v", filename, err)
+	}
+
+	cm.applySaveData(data, false)
+	return nil
+}
+
+// saveTimeSkewTolerance is how far a file's SaveTime may lie in the future,
+// relative to the local clock, before loading it warns about clock skew
+const saveTimeSkewTolerance = 5 * time.Second
+
+// applySaveData updates the chronometers from loaded save data, matching
+// them by ID. With resumeRunning, running timers also count the time since
+// the save; a save time in the future credits nothing.
+func (cm *ChronoManager) applySaveData(data SaveData, resumeRunning bool) {
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	gap := time.Since(data.SaveTime)
+	if gap < 0 {
+		gap = 0
+	}
+
+	cm.loadWarnings = nil
+	if skew := data.SaveTime.Sub(time.Now()); skew > saveTimeSkewTolerance {
+		cm.loadWarnings = append(cm.loadWarnings, fmt.Sprintf(
+			"the file was saved %s in the future; check the clocks of both machines", formatDuration(skew)))
+	}
+
+	if _, ok := statusIndicators[data.Indicators]; ok {
+		cm.indicators = data.Indicators
+	}
+
+	// The timer current when saved becomes current again, or the first one
+	// if it is gone
+	cm.current = 0
+	for i, c := range cm.chronometers {
+		if c.id == data.SelectedID {
+			cm.current = i
+		}
+	}
+
+	// Stop all running chronometers first
+	for i := range cm.chronometers {
+		cm.stopLocked(i)
+	}
+
+	// Update chronometer states
+	for _, cd := range data.Chronometers {
+		// Find the corresponding chronometer by ID
+		for i, c := range cm.chronometers {
+			if c.id == cd.ID {
+				cm.chronometers[i].displayLabel = cd.DisplayLabel
+				cm.chronometers[i].elapsedTime = cd.ElapsedTime
+				if cd.Mode == ChronoModeCountdown {
+					cm.chronometers[i].SetTarget(cd.Target)
+				} else {
+					cm.chronometers[i].SetTarget(0)
+				}
+				cm.chronometers[i].pomodoroPhase = cd.PomodoroPhase
+				cm.chronometers[i].pomodoros = cd.Pomodoros
+				cm.chronometers[i].category = cd.Category
+				cm.chronometers[i].SetMaxDuration(cd.MaxDuration)
+				cm.chronometers[i].undoable = false
+				cm.chronometers[i].epoch++
+				cm.chronometers[i].SetBudget(cd.Budget)
+				cm.chronometers[i].SetOvertimeAt(cd.OvertimeAt)
+				cm.chronometers[i].selected = cd.Selected
+				cm.chronometers[i].segments = cd.Segments
+				cm.chronometers[i].notes = cd.Notes
+				if cd.IsRunning && resumeRunning {
+					// The run still open in the file carries on through
+					// the gap
+					c := cm.chronometers[i]
+					c.elapsedTime += gap
+					c.startTime = time.Now().Add(-c.elapsedTime)
+					c.isRunning = true
+				} else {
+					// A run still open in the file ended when it was saved
+					cm.chronometers[i].closeSegment(data.SaveTime)
+					// If it was running, start it again. Resuming after a
+					// load does not count as another start.
+					if cd.IsRunning {
+						cm.chronometers[i].Start()
+					}
+				}
+				cm.chronometers[i].startCount = cd.StartCount
+				cm.chronometers[i].startedAt = cd.StartedAt
+				cm.chronometers[i].stoppedAt = cd.StoppedAt
+				// Schedules already due fire on the scheduler's next pass
+				cm.chronometers[i].scheduledAt = cd.ScheduledAt
+				cm.chronometers[i].SetGoal(cd.Goal)
+				cm.chronometers[i].laps = cd.Laps
+				cm.chronometers[i].splits = cd.Splits
+				cm.chronometers[i].paused = cd.Paused && !cd.IsRunning
+				cm.chronometers[i].goalMetOn = cd.GoalMetOn
+				break
+			}
+		}
+	}
+}
+
+func (cm *ChronoManager) SaveToCSV(filename string) error {
+	return cm.SaveToCSVFiltered(filename, nil)
+}
+
+// LoadFromCSV restores labels, elapsed times, laps and categories from a CSV
+// written by SaveToCSV, matching timers by ID and skipping the Total row.
+// Columns after "Elapsed Time" other than "Lap N" and "Category" (such as
+// "Goal Met" or "Status") are ignored, and rows for unknown IDs are
+// reported via LoadWarnings. A value that doesn't parse fails the import
+// with a *ParseError before any timer changes.
+func (cm *ChronoManager) LoadFromCSV(filename string) error {
+	filename = cm.resolvePath(filename)
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("empty CSV file")
+	}
+	header := records[0]
+	if len(header) < 3 || header[0] != "Timer ID" || header[1] != "Label" || header[2] != "Elapsed Time" {
+		return fmt.Errorf("line 1: expected a Timer ID, Label, Elapsed Time header")
+	}
+
+	type csvRow struct {
+		line     int
+		id       int
+		label    string
+		elapsed  time.Duration
+		laps     []time.Duration
+		category string
+	}
+	hasCategory := false
+	for _, name := range header {
+		if name == "Category" {
+			hasCategory = true
+		}
+	}
+	var rows []csvRow
+	for i, record := range records[1:] {
+		line := i + 2
+		if len(record) < 3 {
+			return fmt.Errorf("line %d: expected at least 3 columns, got %d", line, len(record))
+		}
+		if record[0] == "" && record[1] == "Total" {
+			continue
+		}
+
+		row := csvRow{line: line, label: record[1]}
+		if row.id, err = strconv.Atoi(record[0]); err != nil {
+			return csvParseError(line, "timer ID", record[0], err)
+		}
+		if row.elapsed, err = parseDuration(record[2]); err != nil {
+			return csvParseError(line, "elapsed time", record[2], err)
+		}
+		for n := 3; n < len(record) && n < len(header); n++ {
+			if header[n] == "Category" {
+				row.category = record[n]
+			}
+			if !strings.HasPrefix(header[n], "Lap ") || record[n] == "" {
+				continue
+			}
+			lap, err := parseDuration(record[n])
+			if err != nil {
+				return csvParseError(line, strings.ToLower(header[n]), record[n], err)
+			}
+			row.laps = append(row.laps, lap)
+		}
+		rows = append(rows, row)
+	}
+
+	cm.mutex.Lock()
+	defer cm.unlockNotify()
+
+	cm.loadWarnings = nil
+	for _, row := range rows {
+		var c *Chronometer
+		for _, candidate := range cm.chronometers {
+			if candidate.id == row.id {
+				c = candidate
+				break
+			}
+		}
+		if c == nil {
+			cm.loadWarnings = append(cm.loadWarnings, fmt.Sprintf("line %d: skipped unknown timer %d", row.line, row.id))
+			continue
+		}
+
+		c.Stop()
+		c.displayLabel = row.label
+		// Countdowns export the time left, not the time counted
+		c.elapsedTime = row.elapsed
+		if c.mode == ChronoModeCountdown {
+			c.elapsedTime = max(c.target-row.elapsed, 0)
+		}
+		c.epoch++
+		c.laps = row.laps
+		c.splits = splitsOf(row.laps)
+		if hasCategory {
+			c.SetCategory(row.category)
+		}
+	}
+
+	return nil
+}
+
+// SaveToCSVFiltered exports only the chronometers for which include returns
+// true. A nil include exports all of them.
+func (cm *ChronoManager) SaveToCSVFiltered(filename string, include func(*Chronometer) bool) error {
+	filename = cm.resolvePath(filename)
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	// Write header, with one column per lap of the timer with the most
+	maxLaps := 0
+	for _, c := range cm.chronometers {
+		if (include == nil || include(c)) && len(c.laps) > maxLaps {
+			maxLaps = len(c.laps)
+		}
+	}
+	header := []string{"Timer ID", "Label", "Elapsed Time", "Goal Met", "Status", "Exported At", "Elapsed (ns)", "Category", "Started At", "Stopped At"}
+	for n := 1; n <= maxLaps; n++ {
+		header = append(header, fmt.Sprintf("Lap %d", n))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	// Write data
+	now := time.Now()
+	exportedAt := now.In(cm.location).Format(time.RFC3339)
+	var total time.Duration
+	for _, c := range cm.chronometers {
+		if include != nil && !include(c) {
+			continue
+		}
+		elapsed := c.GetElapsedTime()
+		total += elapsed
+		met := ""
+		if c.goalMet(now) {
+			met = "✓"
+		}
+		status := "Stopped"
+		if c.isRunning {
+			status = "Running"
+		}
+		record := []string{
+			fmt.Sprintf("%d", c.id),
+			c.displayLabel,
+			cm.formatExport(elapsed),
+			met,
+			status,
+			exportedAt,
+			strconv.FormatInt(int64(elapsed), 10),
+			c.category,
+			cm.formatTimestamp(c.startedAt),
+			cm.formatTimestamp(c.stoppedAt),
+		}
+		for n := 0; n < maxLaps; n++ {
+			lap := ""
+			if n < len(c.laps) {
+				lap = cm.formatExport(c.laps[n])
+			}
+			record = append(record, lap)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	// Footer with the sum of the elapsed times above, which for countdowns
+	// are the times remaining
+	footer := make([]string, len(header))
+	for i, name := range header {
+		switch name {
+		case "Label":
+			footer[i] = "Total"
+		case "Elapsed Time":
+			footer[i] = cm.formatExport(total)
+		case "Elapsed (ns)":
+			footer[i] = strconv.FormatInt(int64(total), 10)
+		}
+	}
+	return writer.Write(footer)
+}
+
+// ExportTimer is one chronometer in the flat JSON export, meant for tools
+// such as jq rather than for loading back
+type ExportTimer struct {
+	ID             int     `json:"id"`
+	Label          string  `json:"label"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	ElapsedHuman   string  `json:"elapsed_human"`
+	Running        bool    `json:"running"`
+	Category       string  `json:"category"`
+}
+
+// ExportJSON writes every chronometer as a flat JSON array of ExportTimer
+func (cm *ChronoManager) ExportJSON(filename string) error {
+	return cm.ExportJSONFiltered(filename, nil)
+}
+
+// ExportJSONFiltered writes the chronometers for which include returns true
+// as a flat JSON array. A nil include exports all of them.
+func (cm *ChronoManager) ExportJSONFiltered(filename string, include func(*Chronometer) bool) error {
+	filename = cm.resolvePath(filename)
+	jsonData, err := json.MarshalIndent(cm.exportTimers(include), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, append(jsonData, '\n'))
+}
+
+// exportTimers lists the chronometers for which include returns true, or
+// all of them if include is nil, in the flat export form
+func (cm *ChronoManager) exportTimers(include func(*Chronometer) bool) []ExportTimer {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	timers := []ExportTimer{}
+	for _, c := range cm.chronometers {
+		if include != nil && !include(c) {
+			continue
+		}
+		elapsed := c.GetElapsedTime()
+		timers = append(timers, ExportTimer{
+			ID:             c.id,
+			Label:          c.displayLabel,
+			ElapsedSeconds: elapsed.Seconds(),
+			ElapsedHuman:   formatDuration(elapsed),
+			Running:        c.isRunning,
+			Category:       c.category,
+		})
+	}
+	return timers
+}
+
+// icsTimeLayout is the UTC date-time form used by iCalendar
+const icsTimeLayout = "20060102T150405Z"
+
+// icsEscape escapes a TEXT value per RFC 5545
+var icsEscape = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// icsFold splits a content line into 75-octet pieces joined by CRLF and a
+// space, without breaking a UTF-8 sequence
+func icsFold(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // the leading space counts towards the next line
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+// SaveToICS exports every recorded run as a calendar event
+func (cm *ChronoManager) SaveToICS(filename string) error {
+	return cm.SaveToICSFiltered(filename, nil)
+}
+
+// SaveToICSFiltered writes a VCALENDAR with one VEVENT per run segment of
+// the chronometers accepted by include. Timers that were never started are
+// skipped and a run still in progress ends now.
+func (cm *ChronoManager) SaveToICSFiltered(filename string, include func(*Chronometer) bool) error {
+	filename = cm.resolvePath(filename)
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	now := time.Now()
+	stamp := now.UTC().Format(icsTimeLayout)
+
+	var lines []string
+	lines = append(lines,
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//metrochrono//metrochrono//EN",
+		"CALSCALE:GREGORIAN",
+	)
+	for _, c := range cm.chronometers {
+		if include != nil && !include(c) {
+			continue
+		}
+		for i, seg := range c.segments {
+			end := seg.End
+			if end.IsZero() {
+				end = now
+			}
+			lines = append(lines,
+				"BEGIN:VEVENT",
+				fmt.Sprintf("UID:%d-%d-%d@metrochrono", c.id, seg.Start.UnixNano(), i),
+				"DTSTAMP:"+stamp,
+				"DTSTART:"+seg.Start.UTC().Format(icsTimeLayout),
+				"DTEND:"+end.UTC().Format(icsTimeLayout),
+				"SUMMARY:"+icsEscape.Replace(c.displayLabel),
+				"END:VEVENT",
+			)
+		}
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(icsFold(line))
+		b.WriteString("\r\n")
+	}
+	return ioutil.WriteFile(filename, []byte(b.String()), 0644)
+}
+
+// maxPlotLabel is the number of characters of a label shown on the chart
+const maxPlotLabel = 24
+
+// truncateLabel shortens label to at most max characters, marking the cut
+// with an ellipsis
+func truncateLabel(label string, max int) string {
+	runes := []rune(label)
+	if len(runes) <= max {
+		return label
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// SavePlot renders a bar chart of every chronometer's elapsed time
+func (cm *ChronoManager) SavePlot(filename string) error {
+	return cm.SavePlotFiltered(filename, nil)
+}
+
+// SavePlotFiltered renders a horizontal bar chart of the elapsed seconds of
+// the chronometers accepted by include that have recorded any time. The
+// image format follows the file extension, e.g. .png. Without any such
+// chronometer a placeholder chart is written.
+func (cm *ChronoManager) SavePlotFiltered(filename string, include func(*Chronometer) bool) error {
+	filename = cm.resolvePath(filename)
+
+	cm.mutex.Lock()
+	var labels []string
+	var values plotter.Values
+	for _, c := range cm.chronometers {
+		if include != nil && !include(c) {
+			continue
+		}
+		if elapsed := c.GetElapsedTime(); elapsed > 0 {
+			labels = append(labels, truncateLabel(c.displayLabel, maxPlotLabel))
+			values = append(values, elapsed.Seconds())
+		}
+	}
+	cm.mutex.Unlock()
+
+	p := plot.New()
+	p.Title.Text = "Elapsed time per timer"
+	p.X.Label.Text = "Elapsed (seconds)"
+	height := 3 * vg.Inch
+
+	if len(values) == 0 {
+		p.Title.Text = "No elapsed time recorded"
+		p.X.Label.Text = ""
+		p.HideAxes()
+	} else {
+		// Bars are drawn bottom-up, so reverse them to list the first
+		// timer at the top
+		for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+			labels[i], labels[j] = labels[j], labels[i]
+			values[i], values[j] = values[j], values[i]
+		}
+		bars, err := plotter.NewBarChart(values, vg.Points(14))
+		if err != nil {
+			return err
+		}
+		bars.Horizontal = true
+		p.Add(bars)
+		p.NominalY(labels...)
+		if h := vg.Length(len(values))*0.3*vg.Inch + 1.5*vg.Inch; h > height {
+			height = h
+		}
+	}
+
+	return p.Save(8*vg.Inch, height, filename)
+}
+
+// LoadWarnings returns the non-fatal problems found by the last load
+func (cm *ChronoManager) LoadWarnings() []string {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	return append([]string(nil), cm.loadWarnings...)
+}
+
+// SaveLapsToCSV exports one row per recorded lap
+func (cm *ChronoManager) SaveLapsToCSV(filename string) error {
+	filename = cm.resolvePath(filename)
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	// Write header
+	if err := writer.Write([]string{"Timer ID", "Lap Number", "Lap Time"}); err != nil {
+		return err
+	}
+
+	// Write data
+	for _, c := range cm.chronometers {
+		for n, lap := range c.laps {
+			if err := writer.Write([]string{
+				fmt.Sprintf("%d", c.id),
+				fmt.Sprintf("%d", n+1),
+				formatDuration(lap),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// LoadLapsFromCSV replaces the laps of every chronometer that appears in a
+// per-lap CSV as written by SaveLapsToCSV. Rows for unknown IDs are skipped,
+// and out-of-sequence lap numbers or decreasing lap times are reported via
+// LoadWarnings rather than failing the import.
+func (cm *ChronoManager) LoadLapsFromCSV(filename string) error {
+	filename = cm.resolvePath(filename)
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("empty laps file")
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.loadWarnings = nil
+	laps := make(map[int][]time.Duration)
+	for i, record := range records[1:] {
+		line := i + 2
+		if len(record) < 3 {
+			return fmt.Errorf("line %d: expected 3 columns, got %d", line, len(record))
+		}
+
+		id, err := strconv.Atoi(record[0])
+		if err != nil {
+			return csvParseError(line, "timer ID", record[0], err)
+		}
+		number, err := strconv.Atoi(record[1])
+		if err != nil {
+			return csvParseError(line, "lap number", record[1], err)
+		}
+		lap, err := parseDuration(record[2])
+		if err != nil {
+			return csvParseError(line, "lap time", record[2], err)
+		}
+
+		known := false
+		for _, c := range cm.chronometers {
+			if c.id == id {
+				known = true
+				break
+			}
+		}
+		if !known {
+			cm.loadWarnings = append(cm.loadWarnings, fmt.Sprintf("line %d: skipped unknown timer %d", line, id))
+			continue
+		}
+
+		previous := laps[id]
+		if number != len(previous)+1 {
+			cm.loadWarnings = append(cm.loadWarnings, fmt.Sprintf("line %d: timer %d lap %d out of sequence", line, id, number))
+		}
+		if len(previous) > 0 && lap < previous[len(previous)-1] {
+			cm.loadWarnings = append(cm.loadWarnings, fmt.Sprintf("line %d: timer %d lap %d is earlier than the previous lap", line, id, number))
+		}
+		laps[id] = append(previous, lap)
+	}
+
+	for _, c := range cm.chronometers {
+		if l, ok := laps[c.id]; ok {
+			c.laps = l
+			c.splits = splitsOf(l)
+		}
+	}
+
+	return nil
+}
+
+// Theme is a color scheme, with each color given as a tview color name
+type Theme struct {
+	Running    string // status of a running timer
+	Paused     string // status of a paused timer
+	Stopped    string // status of a stopped timer
+	Time       string // elapsed times
+	Text       string // all other text
+	Border     string
+	Background string // "default" keeps the terminal's own
+}
+
+// themes are the built-in color schemes selected with -theme. "dark" is
+// tview's own look.
+var themes = map[string]Theme{
+	"dark": {
+		Running: "green", Paused: "yellow", Stopped: "red",
+		Time: "yellow", Text: "white", Border: "white", Background: "black",
+	},
+	"light": {
+		Running: "darkgreen", Paused: "darkorange", Stopped: "darkred",
+		Time: "navy", Text: "black", Border: "black", Background: "default",
+	},
+	"highcontrast": {
+		Running: "lime", Paused: "yellow", Stopped: "red",
+		Time: "white", Text: "white", Border: "white", Background: "black",
+	},
+}
+
+// colorTag returns the tview tag switching the text to color
+func colorTag(color string) string {
+	return "[" + color + "]"
+}
+
+// apply makes the theme's text, border and background colors the defaults
+// of every widget created afterwards
+func (t Theme) apply() {
+	tview.Styles.PrimitiveBackgroundColor = tcell.GetColor(t.Background)
+	tview.Styles.PrimaryTextColor = tcell.GetColor(t.Text)
+	tview.Styles.SecondaryTextColor = tcell.GetColor(t.Time)
+	tview.Styles.BorderColor = tcell.GetColor(t.Border)
+	tview.Styles.TitleColor = tcell.GetColor(t.Text)
+}
+
+// statusIndicator holds the status text, title marker and status color
+// shown for a running, paused or stopped chronometer.
+type statusIndicator struct {
+	running, paused, stopped           string
+	runMarker, pauseMarker, stopMarker string
+	runColor, pauseColor, stopColor    string
+}
+
+// themed returns the indicator with the status colors of the "color" style
+// taken from the theme. Indicators without colors are returned unchanged.
+func (s statusIndicator) themed(t Theme) statusIndicator {
+	if s.runColor == "" {
+		return s
+	}
+	s.runColor, s.pauseColor, s.stopColor = colorTag(t.Running), colorTag(t.Paused), colorTag(t.Stopped)
+	s.runMarker, s.pauseMarker = s.runColor+"● ", s.pauseColor+"● "
+	return s
+}
+
+// statusIndicators maps an indicator style to its texts. The "symbols" style
+// does not rely on color, for color-blind users and monochrome terminals.
+// The colors shown are those of the dark theme; see themed.
+var statusIndicators = map[string]statusIndicator{
+	"color": {
+		running:     "Status: Running",
+		paused:      "Status: Paused",
+		stopped:     "Status: Stopped",
+		runMarker:   "[green]● ",
+		pauseMarker: "[yellow]● ",
+		runColor:    "[green]",
+		pauseColor:  "[yellow]",
+		stopColor:   "[red]",
+	},
+	"symbols": {
+		running:     "Status: [RUN] Running",
+		paused:      "Status: [ll] Paused",
+		stopped:     "Status: [---] Stopped",
+		runMarker:   tview.Escape("[RUN] "),
+		pauseMarker: tview.Escape("[ll] "),
+		stopMarker:  tview.Escape("[---] "),
+	},
+}
+
+// WriteMarkdown writes a GitHub-flavored Markdown table of all chronometers
+// followed by a total row.
+func (cm *ChronoManager) WriteMarkdown(w io.Writer) error {
+	return cm.writeMarkdown(w, true, cm.formatExport)
+}
+
+// writeMarkdown writes the Markdown table with the Timer, Label, Elapsed and
+// Status columns, and the start counts if starts is set. Elapsed times are
+// formatted by format.
+func (cm *ChronoManager) writeMarkdown(w io.Writer, starts bool, format func(time.Duration) string) error {
+	header, separator := "| Timer | Label | Elapsed | Status |", "|------:|-------|--------:|--------|"
+	if starts {
+		header, separator = header+" Starts |", separator+"-------:|"
+	}
+	if _, err := fmt.Fprintf(w, "%s\n%s\n", header, separator); err != nil {
+		return err
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	var total time.Duration
+	for _, c := range cm.chronometers {
+		elapsed := c.GetElapsedTime()
+		total += elapsed
+
+		status := "Stopped"
+		if c.isRunning {
+			status = "Running"
+		}
+
+		label := strings.ReplaceAll(c.displayLabel, "|", "\\|")
+		if c.goalMet(time.Now()) {
+			label += " ✓"
+		}
+		row := fmt.Sprintf("| %d | %s | %s | %s |", c.id, label, format(elapsed), status)
+		if starts {
+			row += fmt.Sprintf(" %d |", c.startCount)
+		}
+		if _, err := fmt.Fprintln(w, row); err != nil {
+			return err
+		}
+	}
+
+	footer := fmt.Sprintf("| | **Total** | **%s** | |", format(total))
+	if starts {
+		footer += " |"
+	}
+	_, err := fmt.Fprintln(w, footer)
+	return err
+}
+
+// ExportMarkdown writes a Markdown table of all chronometers with their
+// Timer, Label, Elapsed and Status, and a total row, to a file, e.g. for
+// pasting into a standup. Unlike the quit summary it leaves out the start
+// counts and gives elapsed times at full precision.
+func (cm *ChronoManager) ExportMarkdown(filename string) error {
+	filename = cm.resolvePath(filename)
+	var buf bytes.Buffer
+	if err := cm.writeMarkdown(&buf, false, formatDuration); err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, buf.Bytes())
+}
+
+// WriteQuitSummary writes the end-of-session report: the Markdown table of
+// all chronometers and how long the session lasted.
+func (cm *ChronoManager) WriteQuitSummary(w io.Writer) error {
+	if err := cm.WriteMarkdown(w); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\nSession duration: %s\n", cm.formatExport(time.Since(cm.sessionStart)))
+	return err
+}
+
+// bigDigitFont is a three-line seven-segment font for the characters that
+// formatDuration emits
+var bigDigitFont = map[rune][3]string{
+	'0': {" _ ", "| |", "|_|"},
+	'1': {"   ", "  |", "  |"},
+	'2': {" _ ", " _|", "|_ "},
+	'3': {" _ ", " _|", " _|"},
+	'4': {"   ", "|_|", "  |"},
+	'5': {" _ ", "|_ ", " _|"},
+	'6': {" _ ", "|_ ", "|_|"},
+	'7': {" _ ", "  |", "  |"},
+	'8': {" _ ", "|_|", "|_|"},
+	'9': {" _ ", "|_|", " _|"},
+	':': {" ", ".", "."},
+	'.': {" ", " ", "."},
+}
+
+// renderBigDigits renders s in bigDigitFont. Characters missing from the
+// font are rendered as blanks.
+func renderBigDigits(s string) string {
+	var lines [3]strings.Builder
+	for _, r := range s {
+		glyph, ok := bigDigitFont[r]
+		if !ok {
+			glyph = [3]string{" ", " ", " "}
+		}
+		for i := range lines {
+			lines[i].WriteString(glyph[i])
+		}
+	}
+	return lines[0].String() + "\n" + lines[1].String() + "\n" + lines[2].String()
+}
+
+// runMini runs a borderless view showing only the big time of one
+// chronometer, for use as an unobtrusive on-screen clock. It is controlled
+// from the keyboard: space toggles, s starts, x stops, r resets, n and p
+// switch to the next or previous timer, and Esc quits. The time is shown
+// with digits fractional digits and redrawn as often as the last one steps.
+func runMini(app *tview.Application, manager *ChronoManager, id int, digits int, theme Theme) error {
+	view := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(true)
+
+	go func() {
+		for {
+			time.Sleep(refreshInterval(digits))
+			app.QueueUpdateDraw(func() {
+				manager.StopFinished(time.Now())
+				c, _ := manager.copyOf(id)
+				view.SetText(fmt.Sprintf("%s%s\n[-]%s", colorTag(theme.Time),
+					tview.Escape(renderBigDigits(formatDurationPrecision(c.GetElapsedTime(), digits))),
+					tview.Escape(c.displayLabel)))
+			})
+		}
+	}()
+
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			app.Stop()
+			return nil
+		}
+
+		switch event.Rune() {
+		case ' ':
+			manager.ToggleChronometer(id)
+		case 's':
+			manager.StartChronometer(id)
+		case 'x':
+			manager.StopChronometer(id)
+		case 'r':
+			manager.ResetChronometer(id)
+		case 'n':
+			id = (id + 1) % manager.Count()
+		case 'p':
+			id = (id + manager.Count() - 1) % manager.Count()
+		default:
+			return event
+		}
+		return nil
+	})
+
+	return app.SetRoot(view, true).Run()
+}
+
+// parseSpeed parses a replay speed such as "10x", "0.5x" or "2"
+func parseSpeed(s string) (float64, error) {
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "x"), 64)
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("invalid speed %q", s)
+	}
+	return speed, nil
+}
+
+// parseClock parses a wall-clock time of day written as HH:MM
+func parseClock(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q: use HH:MM", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// nextDailyAt returns the next instant strictly after now at which the wall
+// clock in now's location reads hour:minute. time.Date normalizes times
+// skipped or repeated by DST transitions, so the result is always one
+// calendar day's occurrence.
+func nextDailyAt(now time.Time, hour, minute int) time.Time {
+	y, m, d := now.Date()
+	next := time.Date(y, m, d, hour, minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = time.Date(y, m, d+1, hour, minute, 0, 0, now.Location())
+	}
+	return next
+}
+
+// datedFilename inserts day's date before the extension of name, so daily
+// saves do not overwrite each other
+func datedFilename(name string, day time.Time) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + day.Format("2006-01-02") + ext
+}
+
+// replaySpan returns the first and last instants covered by the recorded
+// segments. Runs still open in the file end at its SaveTime.
+func replaySpan(data SaveData) (begin, end time.Time, ok bool) {
+	for _, cd := range data.Chronometers {
+		for _, seg := range cd.Segments {
+			segEnd := seg.End
+			if segEnd.IsZero() {
+				segEnd = data.SaveTime
+			}
+			if !ok || seg.Start.Before(begin) {
+				begin = seg.Start
+			}
+			if !ok || segEnd.After(end) {
+				end = segEnd
+			}
+			ok = true
+		}
+	}
+	return begin, end, ok
+}
+
+// replayState reconstructs the elapsed time and running state of a recorded
+// chronometer at the given instant. Elapsed time not covered by segments,
+// e.g. carried over from an older save file, is treated as a fixed offset.
+func replayState(cd ChronoData, saveTime, at time.Time) (time.Duration, bool) {
+	var recorded, elapsed time.Duration
+	running := false
+	for _, seg := range cd.Segments {
+		end := seg.End
+		if end.IsZero() {
+			end = saveTime
+		}
+		recorded += end.Sub(seg.Start)
+
+		switch {
+		case !at.After(seg.Start):
+			// Not started yet at this instant
+		case at.Before(end):
+			elapsed += at.Sub(seg.Start)
+			running = true
+		default:
+			elapsed += end.Sub(seg.Start)
+		}
+	}
+
+	offset := cd.ElapsedTime - recorded
+	if offset < 0 {
+		offset = 0
+	}
+	return offset + elapsed, running
+}
+
+// runReplay animates the timers recorded in a save file at the given speed.
+// It is read-only: the file is never written. Space pauses and resumes the
+// replay and Esc quits.
+func runReplay(app *tview.Application, filename string, speed float64, indicator statusIndicator, theme Theme) error {
+	jsonData, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var data SaveData
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return err
+	}
+
+	begin, end, ok := replaySpan(data)
+	if !ok {
+		return fmt.Errorf("%s has no recorded segments to replay", filename)
+	}
+
+	header := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(true)
+
+	rows := make([]int, (len(data.Chronometers)+2)/3)
+	cells := tview.NewGrid().
+		SetRows(rows...).
+		SetColumns(0, 0, 0)
+
+	views := make([]*tview.TextView, len(data.Chronometers))
+	for i := range data.Chronometers {
+		view := tview.NewTextView().
+			SetTextAlign(tview.AlignCenter).
+			SetDynamicColors(true)
+		view.SetBorder(true)
+		views[i] = view
+		cells.AddItem(view, i/3, i%3, 1, 1, 0, 0, false)
+	}
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(header, 1, 0, false).
+		AddItem(cells, 0, 1, false)
+
+	// position and paused are only touched on the event loop goroutine
+	var position time.Duration
+	paused := false
+	last := time.Now()
+	span := end.Sub(begin)
+
+	go func() {
+		for {
+			time.Sleep(10 * time.Millisecond)
+			app.QueueUpdateDraw(func() {
+				now := time.Now()
+				if !paused {
+					position += time.Duration(float64(now.Sub(last)) * speed)
+					if position > span {
+						position = span
+					}
+				}
+				last = now
+
+				at := begin.Add(position)
+				state := fmt.Sprintf("%gx", speed)
+				if paused {
+					state = "paused"
+				} else if position == span {
+					state = "finished"
+				}
+				header.SetText(fmt.Sprintf("Replay of %s  %s%s[-]  %s  (space: pause/resume, Esc: quit)",
+					tview.Escape(filename), colorTag(theme.Time), at.Format("2006-01-02 15:04:05"), state))
+
+				for i, cd := range data.Chronometers {
+					elapsed, running := replayState(cd, data.SaveTime, at)
+					marker := indicator.stopMarker
+					if running {
+						marker = indicator.runMarker
+					}
+					views[i].SetTitle(fmt.Sprintf(" Timer %d %s", cd.ID, marker))
+					views[i].SetText(fmt.Sprintf("%s\n%s%s", tview.Escape(cd.DisplayLabel), colorTag(theme.Time), formatDuration(elapsed)))
+				}
+			})
+		}
+	}()
+
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			app.Stop()
+			return nil
+		}
+		if event.Rune() == ' ' {
+			paused = !paused
+			return nil
+		}
+		return event
+	})
+
+	return app.SetRoot(layout, true).Run()
+}
+
+// desktopNotify shows an OS notification using whichever tool the platform
+// provides: notify-send on Linux and BSD, osascript on macOS and PowerShell
+// on Windows. It returns an error if the tool is missing or fails.
+func desktopNotify(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		quote := func(s string) string {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		}
+		cmd = exec.Command("osascript", "-e",
+			fmt.Sprintf("display notification %s with title %s", quote(message), quote(title)))
+	case "windows":
+		quote := func(s string) string {
+			return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+		}
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", fmt.Sprintf(
+			"Add-Type -AssemblyName System.Windows.Forms; "+
+				"$n = New-Object System.Windows.Forms.NotifyIcon; "+
+				"$n.Icon = [System.Drawing.SystemIcons]::Information; $n.Visible = $true; "+
+				"$n.ShowBalloonTip(5000, %s, %s, 'Info'); Start-Sleep -Seconds 6; $n.Dispose()",
+			quote(title), quote(message)))
+	default:
+		cmd = exec.Command("notify-send", title, message)
+	}
+
+	if _, err := exec.LookPath(cmd.Path); err != nil {
+		return err
+	}
+	return cmd.Run()
+}
+
+// rateLimiter allows each client IP a fixed number of requests per window.
+// Windows that have run out are dropped once per window, so clients that
+// stop sending don't stay in memory.
+type rateLimiter struct {
+	limit   int
+	window  time.Duration
+	mutex   sync.Mutex
+	clients map[string]*rateWindow
+	swept   time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		window:  window,
+		clients: make(map[string]*rateWindow),
+	}
+}
+
+// Allow reports whether another request from ip fits in the current window
+func (rl *rateLimiter) Allow(ip string, now time.Time) bool {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if now.Sub(rl.swept) >= rl.window {
+		for client, w := range rl.clients {
+			if now.Sub(w.start) >= rl.window {
+				delete(rl.clients, client)
+			}
+		}
+		rl.swept = now
+	}
+
+	w, ok := rl.clients[ip]
+	if !ok || now.Sub(w.start) >= rl.window {
+		rl.clients[ip] = &rateWindow{start: now, count: 1}
+		return true
+	}
+	if w.count >= rl.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// clientIP returns the IP address of the request's remote end
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requireToken rejects requests without an "Authorization: Bearer <token>"
+// header matching token. An empty token disables the check.
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimited rejects requests from clients over their limit. A nil limiter
+// disables the check.
+func rateLimited(limiter *rateLimiter, next http.Handler) http.Handler {
+	if limiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !limiter.Allow(clientIP(r), time.Now()) {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newAPIHandler returns the HTTP control API. GET /timers lists the timers;
+// POST /start, /stop and /reset take the timer ID listed there as ?id=N.
+// Mutation endpoints are subject to the rate limiter.
+func newAPIHandler(manager *ChronoManager, token string, limiter *rateLimiter) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/timers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		all := manager.Find(nil)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(all)
+	})
+
+	mutation := func(action func(id int)) http.Handler {
+		return rateLimited(limiter, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			id, err := strconv.Atoi(r.URL.Query().Get("id"))
+			index, ok := manager.IndexOf(id)
+			if err != nil || !ok {
+				http.Error(w, "unknown timer id", http.StatusNotFound)
+				return
+			}
+			action(index)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	}
+
+	mux.Handle("/start", mutation(manager.StartChronometer))
+	mux.Handle("/stop", mutation(manager.StopChronometer))
+	mux.Handle("/reset", mutation(manager.ResetChronometer))
+
+	return requireToken(token, mux)
+}
+
+// TimerView is the panel showing one chronometer: its label field, the
+// elapsed time, the control buttons, a status line and a selection checkbox
+type TimerView struct {
+	*tview.Flex
+
+	app      *tview.Application
+	manager  *ChronoManager
+	id       int
+	guard    displayGuard
+	label    *tview.InputField
+	time     *tview.TextView
+	progress *tview.TextView
+	buttons  *tview.Flex
+	start    *tview.Button
+	pause    *tview.Button
+	status   *tview.TextView
+	selected *tview.Checkbox
+	stop     func(id int)
+	done     func()
+	// flashUntil is when a border flash started by Flash ends
+	flashUntil time.Time
+}
+
+// idleIndicators lists the ways -idle-indicator can flag that no timer is
+// running: dim the times, or frame all timers with an IDLE banner
+var idleIndicators = []string{"off", "dim", "banner"}
+
+// tempoWindow is the number of recent tap intervals the tempo averages
+const tempoWindow = 4
+
+// TimerViewStyle holds the display settings applied by TimerView.Update
+type TimerViewStyle struct {
+	Digits      int
+	DayCounter  bool
+	Indicator   statusIndicator
+	Theme       Theme
+	Dense       bool
+	TitleLabels bool
+	Dim         bool
+}
+
+// NewTimerView builds the panel for the chronometer at index id
+func NewTimerView(app *tview.Application, manager *ChronoManager, id int) *TimerView {
+	chron, _ := manager.copyOf(id)
+	v := &TimerView{
+		Flex:    tview.NewFlex().SetDirection(tview.FlexRow),
+		app:     app,
+		manager: manager,
+		id:      id,
+		stop:    manager.StopChronometer,
+		done:    func() {},
+	}
+
+	// Label input for this chronometer. A field width of 0 fills whatever
+	// width the box has at each draw, so the field follows terminal resizes
+	// and longer labels scroll inside it.
+	v.label = tview.NewInputField().
+		SetLabel("Label: ").
+		SetText(chron.displayLabel).
+		SetFieldWidth(0)
+
+	// Timer display
+	v.time = tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(true).
+		SetText("[yellow]00:00:00.000")
+	v.time.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		if action == tview.MouseLeftDoubleClick {
+			v.EditElapsed()
+			return action, nil
+		}
+		return action, event
+	})
+
+	// Progress towards the target or maximum duration, hidden by Update
+	// for timers with neither
+	v.progress = tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(true)
+
+	// Timer buttons
+	startButton := tview.NewButton("Start").SetSelectedFunc(func() {
+		manager.StartChronometer(id)
+	})
+	v.start = startButton
+
+	stopButton := tview.NewButton("Stop").SetSelectedFunc(func() {
+		v.stop(id)
+	})
+
+	v.pause = tview.NewButton("Pause").SetSelectedFunc(func() {
+		if c, _ := manager.copyOf(id); c.paused {
+			manager.ResumeChronometer(id)
+		} else {
+			manager.PauseChronometer(id)
+		}
+	})
+
+	resetButton := tview.NewButton("Reset").SetSelectedFunc(func() {
+		manager.ResetChronometer(id)
+	})
+
+	restartButton := tview.NewButton("Restart").SetSelectedFunc(func() {
+		manager.RestartChronometer(id)
+	})
+
+	lapButton := tview.NewButton("Lap").SetSelectedFunc(func() {
+		manager.LapChronometer(id)
+	})
+
+	budgetButton := tview.NewButton("Targets").SetSelectedFunc(v.editTargets)
+	countdownButton := tview.NewButton("Countdown").SetSelectedFunc(v.editCountdown)
+
+	v.buttons = tview.NewFlex().SetDirection(tview.FlexColumn).
+		AddItem(startButton, 0, 1, false).
+		AddItem(stopButton, 0, 1, false).
+		AddItem(v.pause, 0, 1, false).
+		AddItem(resetButton, 0, 1, false).
+		AddItem(restartButton, 0, 1, false).
+		AddItem(lapButton, 0, 1, false).
+		AddItem(budgetButton, 0, 1, false).
+		AddItem(countdownButton, 0, 1, false)
+
+	// Status text
+	v.status = tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(true).
+		SetText("Status: Stopped")
+
+	// Selection checkbox used by the "selected" export filter
+	v.selected = tview.NewCheckbox().
+		SetLabel("Select: ").
+		SetChecked(chron.selected).
+		SetChangedFunc(func(checked bool) {
+			manager.BulkSet([]int{id}, func(c *Chronometer) { c.selected = checked })
+		})
+
+	v.AddItem(v.label, 3, 0, true).
+		AddItem(v.time, 3, 0, false).
+		AddItem(v.progress, 0, 0, false).
+		AddItem(v.buttons, 3, 0, false).
+		AddItem(v.status, 1, 0, false).
+		AddItem(v.selected, 1, 0, false)
+	v.SetBorder(true).SetTitle(fmt.Sprintf(" Timer %d ", chron.id))
+
+	// Any key or click within the view counts as an interaction for the
+	// idle stop
+	v.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		manager.Touch(id)
+		return event
+	})
+	v.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		if action == tview.MouseLeftClick {
+			manager.Touch(id)
+		}
+		return action, event
+	})
+
+	// Focusing any widget of the view, by Tab or by click, makes this
+	// the timer keyboard shortcuts act on
+	current := func() { manager.SetCurrent(id) }
+	v.label.SetFocusFunc(current)
+	v.selected.SetFocusFunc(current)
+	for _, b := range []*tview.Button{startButton, stopButton, v.pause, resetButton,
+		restartButton, lapButton, budgetButton, countdownButton} {
+		b.SetFocusFunc(current)
+	}
+
+	return v
+}
+
+// SetStopFunc replaces what the Stop button does, e.g. to prompt for a note
+func (v *TimerView) SetStopFunc(stop func(id int)) *TimerView {
+	v.stop = stop
+	return v
+}
+
+// SetDoneFunc sets the function called when a dialog opened from the view
+// is closed, which should restore the main screen
+func (v *TimerView) SetDoneFunc(done func()) *TimerView {
+	v.done = done
+	return v
+}
+
+// LabelField returns the label input field
+func (v *TimerView) LabelField() *tview.InputField {
+	return v.label
+}
+
+// ChronoID returns the ID of the chronometer shown, which unlike the index
+// the view was built for stays with the timer
+func (v *TimerView) ChronoID() int {
+	c, _ := v.manager.copyOf(v.id)
+	return c.id
+}
+
+// Flash turns the border red for d; Update restores it afterwards
+func (v *TimerView) Flash(d time.Duration) {
+	v.flashUntil = time.Now().Add(d)
+	v.SetBorderColor(tcell.ColorRed)
+}
+
+// Controls returns the Start button, which takes focus back from the label
+func (v *TimerView) Controls() tview.Primitive {
+	return v.buttons.GetItem(0)
+}
+
+// ToggleSelected flips the selection checkbox and the chronometer's
+// selected flag, returning the new state
+func (v *TimerView) ToggleSelected() bool {
+	selected := !v.selected.IsChecked()
+	v.selected.SetChecked(selected)
+	v.manager.BulkSet([]int{v.id}, func(c *Chronometer) { c.selected = selected })
+	return selected
+}
+
+// Refresh reloads the label and selection from the chronometer, after they
+// were changed outside the view
+func (v *TimerView) Refresh() {
+	c, _ := v.manager.copyOf(v.id)
+	v.label.SetText(c.displayLabel)
+	v.selected.SetChecked(c.selected)
+}
+
+// SetLayout switches between the boxed and the dense layout, and shows or
+// hides the label row
+func (v *TimerView) SetLayout(dense, showLabel bool) {
+	items := []tview.Primitive{v.label, v.time, v.buttons, v.status, v.selected}
+	heights := []int{3, 3, 3, 1, 1}
+	if dense {
+		heights = []int{1, 2, 1, 1, 1}
+	}
+	if !showLabel {
+		heights[0] = 0
+	}
+	v.SetBorder(!dense)
+	for n, height := range heights {
+		v.ResizeItem(items[n], height, 0)
+	}
+}
+
+// Update redraws the elapsed time and, unless a label is being edited, the
+// title and status line
+func (v *TimerView) Update(style TimerViewStyle, editing bool) {
+	c, _ := v.manager.copyOf(v.id)
+
+	if !v.flashUntil.IsZero() && !time.Now().Before(v.flashUntil) {
+		v.flashUntil = time.Time{}
+		v.SetBorderColor(tview.Styles.BorderColor)
+	}
+
+	elapsed := v.guard.apply(c.GetElapsedTime(), c.isRunning, c.mode == ChronoModeCountdown, c.epoch)
+	formatted := formatDurationWithDaysPrecision(elapsed, style.Digits)
+	if style.DayCounter {
+		formatted = formatDayCounter(elapsed)
+	}
+	color := style.Theme.Time
+	switch {
+	case style.Dim:
+		color = "gray"
+	case c.pomodoroPhase == PomodoroWork:
+		color = style.Theme.Stopped
+	case c.pomodoroPhase == PomodoroBreak:
+		color = style.Theme.Running
+	}
+	text := fmt.Sprintf("[%s]%s", color, formatted)
+	if c.mode == ChronoModeStopwatch && c.overtimeAt > 0 && elapsed > c.overtimeAt {
+		text = fmt.Sprintf("[red]+%s over", formatDurationWithDaysPrecision(elapsed-c.overtimeAt, style.Digits))
+	}
+	if c.budget > 0 {
+		text += "\n[-]" + formatBudget(elapsed, c.budget)
+	}
+	if n := len(c.laps); c.tapping {
+		if n > 0 {
+			text += fmt.Sprintf("\n[-]%.1f BPM (%d taps)", v.manager.Tempo(v.id, tempoWindow), n+1)
+		} else {
+			text += "\n[-]Tap again for the tempo"
+		}
+	} else if n > 0 {
+		text += fmt.Sprintf("\n[-]Lap %d: %s", n, formatDuration(c.laps[n-1]))
+	}
+	v.time.SetText(text)
+
+	if c.limit() > 0 {
+		_, _, width, _ := v.progress.GetInnerRect()
+		v.progress.SetText(renderProgressBar(progressFraction(&c), max(width, 15), style.Theme.Running))
+		v.ResizeItem(v.progress, 1, 0)
+	} else {
+		v.ResizeItem(v.progress, 0, 0)
+	}
+
+	// Rewriting titles and status lines while a label is being typed can
+	// make the cursor jump in some terminals
+	if editing {
+		return
+	}
+
+	v.start.SetLabelColor(tcell.GetColor(style.Theme.Running))
+	if c.paused {
+		v.pause.SetLabel("Resume")
+	} else {
+		v.pause.SetLabel("Pause")
+	}
+	status, marker, color := style.Indicator.stopped, style.Indicator.stopMarker, style.Indicator.stopColor
+	if c.isRunning {
+		status, marker, color = style.Indicator.running, style.Indicator.runMarker, style.Indicator.runColor
+	} else if c.paused {
+		status, marker, color = style.Indicator.paused, style.Indicator.pauseMarker, style.Indicator.pauseColor
+	} else if c.idleStopped {
+		// Stopped by the idle stop rather than by hand
+		status, color = style.Indicator.stopped+" (idle)", style.Indicator.pauseColor
+	}
+	title := fmt.Sprintf("Timer %d", c.id)
+	if style.TitleLabels {
+		title += ": " + tview.Escape(c.displayLabel)
+	}
+	if c.category != "" {
+		title += " (" + tview.Escape(c.category) + ")"
+	}
+	if !c.scheduledAt.IsZero() && !c.isRunning {
+		title += " starts in " + formatStartsIn(time.Until(c.scheduledAt))
+	}
+	if v.manager.GoalMet(v.id) {
+		title += " ✓"
+	}
+	if c.idleStopped && !c.isRunning {
+		title += " idle"
+	}
+	switch c.pomodoroPhase {
+	case PomodoroWork:
+		title += fmt.Sprintf(" Work (%d done)", c.pomodoros)
+	case PomodoroBreak:
+		title += fmt.Sprintf(" Break (%d done)", c.pomodoros)
+	}
+	v.SetTitle(fmt.Sprintf(" %s %s", title, marker))
+	if c.startCount > 0 {
+		status += fmt.Sprintf("  Starts: %d", c.startCount)
+	}
+	switch {
+	case c.isRunning || c.stoppedAt.IsZero():
+		status += "  Started: " + formatClock(c.startedAt)
+	default:
+		status += "  Stopped: " + formatClock(c.stoppedAt)
+	}
+	if style.Dense {
+		// Without borders the title moves into the status line
+		v.status.SetText(fmt.Sprintf("%s %s[-]%s%s", title, marker, color, tview.Escape(status)))
+	} else {
+		v.status.SetText(color + tview.Escape(status))
+	}
+}
+
+// editTargets opens the form setting the budget and overtime mark
+func (v *TimerView) editTargets() {
+	c, _ := v.manager.copyOf(v.id)
+	currentBudget, currentOvertime, currentGoal, currentMax := "", "", "", ""
+	if c.budget > 0 {
+		currentBudget = formatDuration(c.budget)
+	}
+	if c.overtimeAt > 0 {
+		currentOvertime = formatDuration(c.overtimeAt)
+	}
+	if c.goal > 0 {
+		currentGoal = formatDuration(c.goal)
+	}
+	if c.maxDuration > 0 {
+		currentMax = formatDuration(c.maxDuration)
+	}
+	form := tview.NewForm()
+	form.AddInputField("Budget (e.g. 1h30m)", currentBudget, 20, nil, nil)
+	form.AddInputField("Overtime at (e.g. 30m)", currentOvertime, 20, nil, nil)
+	form.AddInputField("Daily goal (e.g. 30m)", currentGoal, 20, nil, nil)
+	form.AddInputField("Stop at (e.g. 2h)", currentMax, 20, nil, nil)
+	form.AddTextView("", "", 40, 1, true, false)
+	form.AddButton("Set", func() {
+		durations := make([]time.Duration, 4)
+		for n := range durations {
+			text := strings.TrimSpace(form.GetFormItem(n).(*tview.InputField).GetText())
+			if text == "" {
+				continue
+			}
+			d, err := parseHuman(text)
+			if err != nil {
+				// Show the error inline and keep the form open
+				form.GetFormItem(len(durations)).(*tview.TextView).SetText("[red]" + tview.Escape(err.Error()))
+				return
+			}
+			durations[n] = d
+		}
+		v.manager.BulkSet([]int{v.id}, func(c *Chronometer) {
+			c.SetBudget(durations[0])
+			c.SetOvertimeAt(durations[1])
+			c.SetGoal(durations[2])
+			c.SetMaxDuration(durations[3])
+		})
+		v.done()
+	})
+	form.AddButton("Cancel", func() {
+		v.done()
+	})
+	form.SetBorder(true).SetTitle(fmt.Sprintf("Targets for Timer %d", c.id))
+	form.SetCancelFunc(func() {
+		v.done()
+	})
+	v.app.SetRoot(form, true)
+}
+
+// EditElapsed opens the form that sets the elapsed time, or the time left of
+// a countdown, as HH:MM:SS.mmm. A value that doesn't parse is reported in the
+// form and the old one is kept.
+func (v *TimerView) EditElapsed() {
+	c, _ := v.manager.copyOf(v.id)
+	label := "Elapsed (HH:MM:SS.mmm)"
+	if c.mode == ChronoModeCountdown {
+		label = "Time left (HH:MM:SS.mmm)"
+	}
+	form := tview.NewForm()
+	form.AddInputField(label, formatDuration(c.GetElapsedTime()), 20, nil, nil)
+	form.AddTextView("", "", 40, 1, true, false)
+	form.AddButton("Set", func() {
+		d, err := parseDuration(strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText()))
+		// The full parse error does not fit the one-line error field, and
+		// the field label already gives the format
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			err = fmt.Errorf("invalid duration %q", perr.Raw)
+		}
+		if err == nil && c.mode == ChronoModeCountdown {
+			if d > c.target {
+				err = fmt.Errorf("more than the countdown's %s", formatDuration(c.target))
+			}
+			// Countdowns store the time counted, not the time left
+			d = c.target - d
+		}
+		if err == nil {
+			err = v.manager.SetElapsed(v.id, d)
+		}
+		if err != nil {
+			form.GetFormItem(1).(*tview.TextView).SetText("[red]" + tview.Escape(err.Error()))
+			return
+		}
+		v.done()
+	})
+	form.AddButton("Cancel", func() {
+		v.done()
+	})
+	form.SetBorder(true).SetTitle(fmt.Sprintf("Elapsed Time of Timer %d", c.id))
+	form.SetCancelFunc(func() {
+		v.done()
+	})
+	v.app.SetRoot(form, true)
+}
+
+// editCountdown opens the form turning the chronometer into a countdown
+func (v *TimerView) editCountdown() {
+	c, _ := v.manager.copyOf(v.id)
+	current := ""
+	if c.mode == ChronoModeCountdown {
+		current = formatDuration(c.target)
+	}
+	form := tview.NewForm()
+	form.AddInputField("Count down from (e.g. 25m)", current, 20, nil, nil)
+	form.AddTextView("", "", 40, 1, true, false)
+	form.AddButton("Set", func() {
+		text := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		var target time.Duration
+		if text != "" {
+			var err error
+			target, err = parseHuman(text)
+			if err != nil {
+				form.GetFormItem(1).(*tview.TextView).SetText("[red]" + tview.Escape(err.Error()))
+				return
+			}
+		}
+		v.manager.BulkSet([]int{v.id}, func(c *Chronometer) { c.SetTarget(target) })
+		v.done()
+	})
+	form.AddButton("Cancel", func() {
+		v.done()
+	})
+	form.SetBorder(true).SetTitle(fmt.Sprintf("Countdown for Timer %d", c.id))
+	form.SetCancelFunc(func() {
+		v.done()
+	})
+	v.app.SetRoot(form, true)
+}
+
+// focusScreen passes terminal focus changes to onFocus. tview drops focus
+// events, so they are picked off as the application polls for events.
+type focusScreen struct {
+	tcell.Screen
+	onFocus func(focused bool)
+}
+
+func (s *focusScreen) PollEvent() tcell.Event {
+	event := s.Screen.PollEvent()
+	if focus, ok := event.(*tcell.EventFocus); ok {
+		s.onFocus(focus.Focused)
+	}
+	return event
+}
+
+// Keymap maps action names to the key that triggers them: a single
+// character such as "p", "Space", "Esc" or a control key such as "Ctrl-S"
+type Keymap map[string]string
+
+// defaultKeymap returns the bindings used unless the config file changes them
+func defaultKeymap() Keymap {
+	return Keymap{
+		"exit":         "Esc",
+		"quit":         "Ctrl-Q",
+		"interrupt":    "Ctrl-C",
+		"save":         "Ctrl-S",
+		"load":         "Ctrl-O",
+		"export":       "Ctrl-E",
+		"export-ics":   "Ctrl-K",
+		"export-chart": "Ctrl-G",
+		"export-md":    "Ctrl-T",
+		"laps":         "Ctrl-L",
+		"compare":      "C",
+		"filter":       "Ctrl-F",
+		"reset-labels": "Ctrl-R",
+		"bulk":         "Ctrl-B",
+		"snapshot":     "p",
+		"precision":    "c",
+		"hide-panel":   "h",
+		"select":       "Space",
+		"leaderboard":  "l",
+		"schedule":     "s",
+		"shares":       "w",
+		"tap":          "t",
+		"details":      "i",
+		"rename":       "r",
+		"dense":        "d",
+		"columns":      "g",
+		"adjust-up":    "+",
+		"adjust-down":  "-",
+		"exclusive":    "m",
+		"add-timer":    "n",
+		"remove-timer": "Ctrl-D",
+		"autosave":     "Ctrl-A",
+		"start":        "S",
+		"stop":         "X",
+		"reset-timer":  "R",
+		"restart":      "T",
+		"undo-reset":   "Ctrl-Z",
+		"sort":         "o",
+		"search":       "/",
+		"pomodoro":     "P",
+		"category":     "e",
+		"edit-elapsed": "E",
+		"merge":        "M",
+		"focus-1":      "1",
+		"focus-2":      "2",
+		"focus-3":      "3",
+		"focus-4":      "4",
+		"focus-5":      "5",
+		"focus-6":      "6",
+		"focus-7":      "7",
+		"focus-8":      "8",
+		"focus-9":      "9",
+	}
+}
+
+// terminalKeys are the control keys terminals send as other keys: tcell
+// reports Ctrl-H, Ctrl-I and Ctrl-M as Backspace, Tab and Enter, so actions
+// bound to them could never fire
+var terminalKeys = map[string]string{"Ctrl-H": "Backspace", "Ctrl-I": "Tab", "Ctrl-M": "Enter"}
+
+// normalizeKey returns the canonical spelling of a key name, accepting any
+// case for the named keys and "Ctrl+" for "Ctrl-"
+func normalizeKey(key string) (string, error) {
+	if utf8.RuneCountInString(key) == 1 {
+		return key, nil
+	}
+	lower := strings.ToLower(key)
+	switch lower {
+	case "space":
+		return "Space", nil
+	case "esc", "escape":
+		return "Esc", nil
+	}
+	for _, prefix := range []string{"ctrl-", "ctrl+"} {
+		if rest := strings.TrimPrefix(lower, prefix); rest != lower && len(rest) == 1 && rest[0] >= 'a' && rest[0] <= 'z' {
+			name := "Ctrl-" + strings.ToUpper(rest)
+			if as, ok := terminalKeys[name]; ok {
+				return "", fmt.Errorf("%s cannot be bound: terminals send it as %s", name, as)
+			}
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown key %q", key)
+}
+
+// keyName returns the canonical name of the key pressed in event, matching
+// the spelling normalizeKey produces
+func keyName(event *tcell.EventKey) string {
+	if event.Key() == tcell.KeyRune {
+		if event.Modifiers()&tcell.ModCtrl != 0 {
+			return "Ctrl-" + strings.ToUpper(string(event.Rune()))
+		}
+		if event.Rune() == ' ' {
+			return "Space"
+		}
+		return string(event.Rune())
+	}
+	return tcell.KeyNames[event.Key()]
+}
+
+// bindings returns the reverse of the keymap, from key to action. It fails
+// on unknown actions or keys and on keys bound to more than one action,
+// listing every clash.
+func (k Keymap) bindings() (map[string]string, error) {
+	known := defaultKeymap()
+	actions := make(map[string][]string)
+	for action, key := range k {
+		if _, ok := known[action]; !ok {
+			return nil, fmt.Errorf("unknown key binding action %q", action)
+		}
+		name, err := normalizeKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("key binding for %s: %v", action, err)
+		}
+		actions[name] = append(actions[name], action)
+	}
+
+	byKey := make(map[string]string, len(actions))
+	var clashes []string
+	for key, bound := range actions {
+		if len(bound) > 1 {
+			sort.Strings(bound)
+			clashes = append(clashes, fmt.Sprintf("%s is bound to %s", key, strings.Join(bound, ", ")))
+			continue
+		}
+		byKey[key] = bound[0]
+	}
+	if len(clashes) > 0 {
+		sort.Strings(clashes)
+		return nil, fmt.Errorf("conflicting key bindings: %s", strings.Join(clashes, "; "))
+	}
+	return byKey, nil
+}
+
+// Preferences are the app-wide settings persisted in the config file.
+// Flags given on the command line override them for the session.
+type Preferences struct {
+	Precision       string        `json:"precision"`
+	ExportPrecision string        `json:"exportPrecision"`
+	Indicators      string        `json:"indicators"`
+	Dense           bool          `json:"dense"`
+	TitleLabels     bool          `json:"titleLabels"`
+	DayCounter      bool          `json:"dayCounter"`
+	Binary          bool          `json:"binary"`
+	ExportFilter    string        `json:"exportFilter"`
+	AdjustStep      time.Duration `json:"adjustStep"`
+	Columns         int           `json:"columns"` // 0 for gridColumns
+	Theme           string        `json:"theme"`
+	SaveDir         string        `json:"saveDir"` // empty for the working directory
+	Keys            Keymap        `json:"keys"`
+}
+
+// defaultPreferences returns the settings used when neither the config file
+// nor a flag sets them
+func defaultPreferences() Preferences {
+	return Preferences{
+		Precision:       "milliseconds",
+		ExportPrecision: "milliseconds",
+		Indicators:      "color",
+		Theme:           "dark",
+		ExportFilter:    "all",
+		AdjustStep:      30 * time.Second,
+		Keys:            defaultKeymap(),
+	}
+}
+
+// validate reports the first invalid setting, naming the matching flag
+func (p Preferences) validate() error {
+	if _, ok := precisionDigits[p.Precision]; !ok {
+		return fmt.Errorf("invalid -display-precision %q", p.Precision)
+	}
+	if _, ok := precisionDigits[p.ExportPrecision]; !ok {
+		return fmt.Errorf("invalid -export-precision %q", p.ExportPrecision)
+	}
+	if _, ok := statusIndicators[p.Indicators]; !ok {
+		return fmt.Errorf("invalid indicator style %q", p.Indicators)
+	}
+	if _, ok := exportFilters[p.ExportFilter]; !ok {
+		return fmt.Errorf("invalid -export-filter %q", p.ExportFilter)
+	}
+	if p.AdjustStep <= 0 {
+		return fmt.Errorf("invalid -adjust-step %v: must be positive", p.AdjustStep)
+	}
+	if _, ok := themes[p.Theme]; !ok {
+		return fmt.Errorf("invalid -theme %q: want dark, light or highcontrast", p.Theme)
+	}
+	if p.Columns < 0 {
+		return fmt.Errorf("invalid -columns %d: must not be negative", p.Columns)
+	}
+	if _, err := p.Keys.bindings(); err != nil {
+		return fmt.Errorf("invalid keys in the config file: %v", err)
+	}
+	return nil
+}
+
+// applyFlagOverrides returns prefs with every setting whose flag was given
+// explicitly in fs replaced by the flag's value. Flags left at their
+// defaults don't override persisted settings.
+func applyFlagOverrides(prefs Preferences, fs *flag.FlagSet) Preferences {
+	fs.Visit(func(f *flag.Flag) {
+		value := f.Value.(flag.Getter).Get()
+		switch f.Name {
+		case "precision", "display-precision":
+			prefs.Precision = value.(string)
+		case "export-precision":
+			prefs.ExportPrecision = value.(string)
+		case "symbols":
+			prefs.Indicators = "color"
+			if value.(bool) {
+				prefs.Indicators = "symbols"
+			}
+		case "dense":
+			prefs.Dense = value.(bool)
+		case "title-labels":
+			prefs.TitleLabels = value.(bool)
+		case "day-counter":
+			prefs.DayCounter = value.(bool)
+		case "binary":
+			prefs.Binary = value.(bool)
+		case "export-filter":
+			prefs.ExportFilter = value.(string)
+		case "adjust-step":
+			prefs.AdjustStep = value.(time.Duration)
+		case "columns":
+			prefs.Columns = value.(int)
+		case "theme":
+			prefs.Theme = value.(string)
+		case "save-dir":
+			prefs.SaveDir = value.(string)
+		}
+	})
+	return prefs
+}
+
+// keepRuntimeChanges returns persisted with the settings that changed
+// during the session, those differing between start and now, taken from
+// now. A setting only overridden by a flag stays as stored, so one-off
+// flags never end up in the config file.
+func keepRuntimeChanges(persisted, start, now Preferences) Preferences {
+	if now.Precision != start.Precision {
+		persisted.Precision = now.Precision
+	}
+	if now.Dense != start.Dense {
+		persisted.Dense = now.Dense
+	}
+	if now.ExportFilter != start.ExportFilter {
+		persisted.ExportFilter = now.ExportFilter
+	}
+	return persisted
+}
+
+// configPath returns the location of the config file
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "metrochrono", "config.json"), nil
+}
+
+// loadPreferences reads the config file on top of the defaults. A missing
+// file yields the defaults.
+func loadPreferences() (Preferences, error) {
+	prefs := defaultPreferences()
+
+	path, err := configPath()
+	if err != nil {
+		return prefs, err
+	}
+
+	jsonData, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return prefs, nil
+	}
+	if err != nil {
+		return prefs, err
+	}
+
+	err = json.Unmarshal(jsonData, &prefs)
+	return prefs, err
+}
+
+// savePreferences writes the config file, creating its directory if needed
+func savePreferences(prefs Preferences) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	jsonData, err := json.MarshalIndent(prefs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, jsonData, 0644)
+}
+
+func main() {
+	flag.String("export-filter", "all", "timers to include in saves and exports: all, running or selected")
+	flag.Duration("adjust-step", 30*time.Second, "step used by +/- to adjust a running countdown")
+	flag.String("theme", "dark", "color theme: dark, light or highcontrast")
+	flag.Bool("symbols", false, "show running/stopped with text symbols instead of color")
+	timers := flag.Int("timers", DefaultTimers, "number of timers at startup")
+	flag.Int("columns", 0, "grid columns, 0 for about as many as rows (cycle with g)")
+	flag.String("save-dir", "", "directory relative save, load and export file names are resolved in (~ is expanded)")
+	maxTimers := flag.Int("max-timers", DefaultMaxTimers, "maximum number of timers")
+	allowDupLabels := flag.Bool("allow-dup-labels", false, "allow several timers to share a label")
+	uniqueLabels := flag.Bool("unique-labels", false, "reject a label already in use instead of numbering it")
+	resumeRunning := flag.Bool("resume-running", false, "on load, credit running timers with the time since they were saved")
+	noteOnStop := flag.Bool("note-on-stop", false, "prompt for a note whenever a timer is stopped")
+	flag.Bool("day-counter", false, "show timers past 24 hours as \"Day N HH:MM:SS\"")
+	flag.Bool("dense", false, "start in the dense layout without timer borders (toggle with d)")
+	flag.Bool("title-labels", false, "show labels in the timer titles instead of a label row (edit with r)")
+	tz := flag.String("tz", "Local", "time zone for saved timestamps: an IANA name such as Europe/Berlin, or UTC")
+	flag.Bool("binary", false, "default to the compact binary save format instead of JSON")
+	desktopNotifyFlag := flag.Bool("desktop-notify", false, "show a desktop notification when a countdown finishes")
+	snapshotFile := flag.String("snapshot-file", "snapshots.jsonl", "JSON Lines file the p key appends progress snapshots to")
+	flag.String("display-precision", "milliseconds", "on-screen precision: seconds, centiseconds, milliseconds or microseconds")
+	flag.String("precision", "milliseconds", "same as -display-precision")
+	flag.String("export-precision", "milliseconds", "precision of elapsed times in CSV exports and summaries")
+	resetStartCount := flag.Bool("reset-start-count", false, "also clear a timer's start count when it is reset")
+	mini := flag.Bool("mini", false, "show a single timer in a minimal, keyboard-driven view")
+	miniTimer := flag.Int("mini-timer", 1, "timer shown in -mini mode")
+	replay := flag.String("replay", "", "replay the recorded segments of a save file instead of running timers")
+	speedFlag := flag.String("speed", "1x", "playback speed for -replay, e.g. 10x")
+	apiAddr := flag.String("api", "", "serve the HTTP control API on this address, e.g. :8080")
+	apiToken := flag.String("api-token", "", "require this bearer token on HTTP control API requests")
+	apiRate := flag.Int("api-rate", 60, "HTTP control API mutations allowed per client IP per minute (0 for no limit)")
+	quitSummary := flag.Bool("quit-summary", false, "print a summary of all timers to stdout on quit")
+	idleIndicator := flag.String("idle-indicator", "off", "flag that no timer is running: off, dim or banner")
+	idleStop := flag.Duration("idle-stop", 0, "stop a running timer left untouched for this long and flag it as idle (0 disables)")
+	pauseOnBlur := flag.Bool("pause-on-blur", false, "pause running timers while the terminal window is not focused, if the terminal reports focus")
+	autosave := flag.String("autosave", "", "save to this JSON file periodically and restore it on startup")
+	autosaveInterval := flag.Duration("autosave-interval", time.Minute, "how often -autosave saves")
+	flag.DurationVar(&RefreshInterval, "refresh", 0, "how often to redraw while a timer runs (default: to suit the display precision)")
+	autosaveKeep := flag.Int("autosave-keep", 3, "number of older -autosave files to keep as backups")
+	diff := flag.String("diff", "", "print the differences between two save files and exit: -diff a.json b.json")
+	resetAt := flag.String("reset-at", "", "reset all timers every day at this local time, as HH:MM")
+	resetSave := flag.String("reset-save", "", "with -reset-at, save the timers to this file, dated, before each reset")
+	recoveryFile := flag.String("recovery-file", "recovery.json", "save to this file on Ctrl-C, SIGINT, SIGTERM or Save & Quit, and offer to restore it at the next start (empty to disable)")
+	headless := flag.Bool("headless", false, "run without the terminal UI, reading commands from stdin or -script, and print the timers as JSON")
+	script := flag.String("script", "", "with -headless, read the commands from this file instead of stdin")
+	flag.Parse()
+
+	// persisted holds the settings as stored in the config file; runtime
+	// changes are written back to it. prefs adds this session's flags on top.
+	persisted, err := loadPreferences()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+	}
+	prefs := applyFlagOverrides(persisted, flag.CommandLine)
+	if err := prefs.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	exportFilter := prefs.ExportFilter
+	precision := prefs.Precision
+	theme := themes[prefs.Theme]
+	theme.apply()
+
+	if *diff != "" {
+		if flag.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "-diff needs two save files: -diff a.json b.json")
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := runDiff(os.Stdout, *diff, flag.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error comparing: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *replay != "" {
+		speed, err := parseSpeed(*speedFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := runReplay(tview.NewApplication(), *replay, speed, statusIndicators[prefs.Indicators].themed(theme), theme); err != nil {
+			fmt.Fprintf(os.Stderr, "Error replaying: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *timers < 1 {
+		fmt.Fprintf(os.Stderr, "invalid -timers %d: need at least one timer\n", *timers)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := checkTimerCount(*timers, *maxTimers); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Create chronometer manager with -timers chronometers
+	manager := NewChronoManager(*timers)
+	manager.SetMaxTimers(*maxTimers)
+	manager.SetAllowDuplicateLabels(*allowDupLabels)
+	manager.SetUniqueLabels(*uniqueLabels)
+	manager.SetResetClearsStartCount(*resetStartCount)
+	manager.SetAutoSaveBackups(*autosaveKeep)
+	if err := manager.SetTimeZone(*tz); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -tz %q: %v\n", *tz, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	manager.indicators = prefs.Indicators
+	manager.SetExportPrecision(prefs.ExportPrecision)
+	if err := manager.SetSaveDir(prefs.SaveDir); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -save-dir: %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *headless {
+		input := io.Reader(os.Stdin)
+		if *script != "" {
+			file, err := os.Open(*script)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading script: %v\n", err)
+				os.Exit(1)
+			}
+			defer file.Close()
+			input = file
+		}
+		if err := runHeadless(manager, input, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	app := tview.NewApplication()
+
+	if *autosaveInterval <= 0 || *autosaveKeep < 0 {
+		fmt.Fprintln(os.Stderr, "-autosave-interval must be positive and -autosave-keep not negative")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if RefreshInterval < 0 {
+		fmt.Fprintln(os.Stderr, "-refresh must not be negative")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *idleStop < 0 {
+		fmt.Fprintln(os.Stderr, "-idle-stop must not be negative")
+		flag.Usage()
+		os.Exit(2)
+	}
+	manager.SetIdleStop(*idleStop)
+
+	validIdle := false
+	for _, name := range idleIndicators {
+		validIdle = validIdle || name == *idleIndicator
+	}
+	if !validIdle {
+		fmt.Fprintf(os.Stderr, "invalid -idle-indicator %q\n", *idleIndicator)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var resetHour, resetMinute int
+	if *resetAt != "" {
+		resetHour, resetMinute, err = parseClock(*resetAt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -reset-at: %v\n", err)
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+
+	// printQuitSummary runs once the terminal has been restored, so the
+	// summary stays visible
+	printQuitSummary := func() {
+		if *quitSummary {
+			if err := manager.WriteQuitSummary(os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing summary: %v\n", err)
+			}
+		}
+	}
+
+	if *mini {
+		if *miniTimer < 1 || *miniTimer > manager.Count() {
+			fmt.Fprintf(os.Stderr, "invalid -mini-timer %d: must be between 1 and %d\n", *miniTimer, manager.Count())
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := runMini(app, manager, *miniTimer-1, precisionDigits[precision], theme); err != nil {
+			panic(err)
+		}
+		printQuitSummary()
+		return
+	}
+
+	// Main layout grid
+	grid := tview.NewGrid().
+		SetRows(1, 0, 1, 1, 3). // Title bar, main area for chronometers, total, 1 row for the event log, 3 rows for buttons
+		SetColumns(0)
+
+	// Autosaving goes to the -autosave file, or autosave.json when it is
+	// only switched on from the keyboard. stopAutoSave is nil while off.
+	autosaveFile := *autosave
+	if autosaveFile == "" {
+		autosaveFile = "autosave.json"
+	}
+	var stopAutoSave func()
+
+	// Title bar showing whether starting a timer stops the others, and how
+	// autosaving is going
+	titleBar := tview.NewTextView().SetDynamicColors(true)
+	updateTitleBar := func() {
+		mode := "exclusive: starting a timer stops the others"
+		if !manager.ExclusiveMode() {
+			mode = "concurrent: timers run side by side"
+		}
+		saved := "off"
+		if stopAutoSave != nil {
+			saved = fmt.Sprintf("every %s, none yet", *autosaveInterval)
+			last, err := manager.LastAutoSave()
+			if err != nil {
+				saved = "[red]failed: " + tview.Escape(err.Error())
+			} else if !last.IsZero() {
+				saved = fmt.Sprintf("every %s, last at %s", *autosaveInterval, last.Format("15:04:05"))
+			}
+		}
+		titleBar.SetText(fmt.Sprintf("[::b]metrochrono[::-] [gray]mode:[-] %s  [gray]autosave:[-] %s", mode, saved))
+	}
+	updateTitleBar()
+
+	// Create a grid for chronometers, with rows and columns set by
+	// placeTimers
+	chronoGrid := tview.NewGrid()
+	chronoGrid.SetBorderColor(tcell.GetColor(theme.Stopped)).
+		SetTitle(" IDLE: no timer is running ").
+		SetTitleColor(tcell.GetColor(theme.Stopped))
+
+	// stopTimer stops the chronometer right away and, with -note-on-stop,
+	// then asks for a note to attach to it
+	stopTimer := func(id int) {
+		c, _ := manager.copyOf(id)
+		manager.StopChronometer(id)
+		if !c.isRunning || !*noteOnStop {
+			return
+		}
+
+		input := tview.NewInputField().
+			SetLabel("Note: ").
+			SetFieldWidth(60)
+		input.SetBorder(true).SetTitle(fmt.Sprintf(" Note for Timer %d (Enter to add, Esc to skip) ", c.id))
+		input.SetDoneFunc(func(key tcell.Key) {
+			if key == tcell.KeyEnter {
+				if text := strings.TrimSpace(input.GetText()); text != "" {
+					manager.AddNote(id, text)
+				}
+			}
+			app.SetRoot(grid, true)
+		})
+
+		prompt := tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(input, 3, 0, true).
+			AddItem(nil, 0, 1, false)
+		app.SetRoot(prompt, true)
+	}
+
+	// Status line below the timers with the time they counted together
+	totalLine := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(true)
+
+	// Event log showing the most recent notice, with older ones kept in the buffer
+	eventLog := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+
+	// Each message starts a new line rather than ending its own, so with the
+	// log scrolled to the end its one row shows the latest message and not
+	// an empty line
+	logged := false
+	logEvent := func(format string, args ...interface{}) {
+		if logged {
+			fmt.Fprint(eventLog, "\n")
+		}
+		logged = true
+		fmt.Fprintf(eventLog, "[gray]%s[-] %s", time.Now().Format("15:04:05"), tview.Escape(fmt.Sprintf(format, args...)))
+		eventLog.ScrollToEnd()
+	}
+
+	// UI for each chronometer, built by layoutTimers. Only the indexes in
+	// shown, those matching the search field, are placed in the grid; the
+	// others keep running unseen.
+	var views []*TimerView
+	var shown []int
+
+	// sortMode indexes SortKeys; the timers start in ID order. order holds
+	// the indexes in that order, as last returned by SortBy.
+	sortMode := 0
+	var order []int
+
+	// Search field above the grid narrowing it down by label
+	searchField := tview.NewInputField().
+		SetLabel("Search: ").
+		SetFieldWidth(0)
+	timersPane := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(searchField, 1, 0, false).
+		AddItem(chronoGrid, 0, 1, true)
+
+	// focusView focuses the view of the chronometer at index id if it is
+	// shown and reports whether it was
+	focusView := func(id int) bool {
+		for _, i := range shown {
+			if i == id {
+				app.SetFocus(views[id].Controls())
+				return true
+			}
+		}
+		return false
+	}
+
+	// applyLayout switches between the boxed layout and the dense one, which
+	// drops the borders and tightens the rows so more fits on screen. With
+	// title labels the label row stays hidden except while renaming.
+	dense := prefs.Dense
+	renaming := -1
+	applyLayout := func() {
+		for i, view := range views {
+			view.SetLayout(dense, !prefs.TitleLabels || i == renaming)
+		}
+		if dense {
+			chronoGrid.SetGap(0, 1)
+		} else {
+			chronoGrid.SetGap(0, 0)
+		}
+	}
+
+	// newView creates the widgets for the chronometer at index id and
+	// commits its label edits through the manager
+	newView := func(id int) *TimerView {
+		view := NewTimerView(app, manager, id).
+			SetStopFunc(stopTimer).
+			SetDoneFunc(func() { app.SetRoot(grid, true) })
+
+		labelInput := view.LabelField()
+		labelInput.SetDoneFunc(func(key tcell.Key) {
+			if prefs.TitleLabels {
+				// Hide the row again and hand focus back to the timer
+				renaming = -1
+				applyLayout()
+				app.SetFocus(view.Controls())
+			}
+			if key == tcell.KeyEscape {
+				// Esc cancels the edit and leaves the field
+				c, _ := manager.copyOf(id)
+				labelInput.SetText(c.displayLabel)
+				app.SetFocus(view.Controls())
+				return
+			}
+			requested := strings.TrimSpace(labelInput.GetText())
+			label, err := manager.RenameChronometer(id, requested)
+			if err != nil {
+				c, _ := manager.copyOf(id)
+				labelInput.SetText(c.displayLabel)
+				modal := tview.NewModal().
+					SetText(fmt.Sprintf("Cannot rename timer %d: %v", c.id, err)).
+					AddButtons([]string{"OK"}).
+					SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+						app.SetRoot(grid, true)
+						if !prefs.TitleLabels {
+							app.SetFocus(labelInput)
+						}
+					})
+				app.SetRoot(modal, false)
+				return
+			}
+			labelInput.SetText(label)
+			if label != requested {
+				labelInput.SetText(label)
+				c, _ := manager.copyOf(id)
+				logEvent("Timer %d renamed to %q: %q is already in use", c.id, label, requested)
+			}
+		})
+		return view
+	}
+
+	// placeTimers fills the grid with the views matching the search field,
+	// in the sort order, in -columns columns or else about as many columns as rows. The last
+	// row may be partly empty.
+	placeTimers := func() {
+		shown = manager.Filter(searchField.GetText())
+		rank := make(map[int]int, len(order))
+		for pos, id := range order {
+			rank[id] = pos
+		}
+		sort.SliceStable(shown, func(i, j int) bool { return rank[shown[i]] < rank[shown[j]] })
+		columns := prefs.Columns
+		if columns == 0 {
+			columns = gridColumns(len(shown))
+		}
+		chronoGrid.Clear()
+		chronoGrid.SetColumns(make([]int, columns)...)
+		chronoGrid.SetRows(make([]int, (len(shown)+columns-1)/columns)...)
+		for i, id := range shown {
+			// Add to the grid - calculate row and column
+			col := i % columns
+			row := i / columns
+			chronoGrid.AddItem(views[id], row, col, 1, 1, 0, 0, false)
+		}
+	}
+
+	// layoutTimers rebuilds the views after timers were added or removed
+	layoutTimers := func() {
+		views = make([]*TimerView, manager.Count())
+		for i := range views {
+			views[i] = newView(i)
+		}
+		order, _ = manager.SortBy(SortKeys[sortMode]) // every SortKeys entry is valid
+		placeTimers()
+		applyLayout()
+	}
+	layoutTimers()
+
+	searchField.SetChangedFunc(func(text string) {
+		placeTimers()
+	})
+	// Enter keeps the search and Esc clears it; both go back to the timers
+	searchField.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			searchField.SetText("")
+		}
+		if !focusView(manager.Current()) && len(shown) > 0 {
+			focusView(shown[0])
+		}
+	})
+
+	// toggleAutoSave starts or stops saving every -autosave-interval
+	toggleAutoSave := func() {
+		if stopAutoSave != nil {
+			stopAutoSave()
+			stopAutoSave = nil
+			logEvent("Autosave off")
+		} else {
+			stopAutoSave = manager.StartAutoSave(autosaveFile, *autosaveInterval)
+			logEvent("Autosaving to %s every %s", autosaveFile, *autosaveInterval)
+		}
+		updateTitleBar()
+	}
+
+	// Button panel at the bottom
+	buttonPanel := tview.NewFlex().SetDirection(tview.FlexColumn)
+
+	// addFormatFields adds the filename field and the selector for the given
+	// formats, shared by the save and load forms. JSON and Binary must come
+	// first. Switching the format swaps the default filename's extension.
+	defaultFilenames := map[string]string{"JSON": "timers.json", "Binary": "timers.bin", "YAML": "timers.yaml", "CSV": "timers.csv"}
+	addFormatFields := func(form *tview.Form, formats ...string) {
+		initial := 0
+		if prefs.Binary {
+			initial = 1
+		}
+		form.AddInputField("Filename", defaultFilenames[formats[initial]], 20, nil, nil)
+		form.AddDropDown("Format", formats, initial, func(option string, index int) {
+			input := form.GetFormItem(0).(*tview.InputField)
+			for _, filename := range defaultFilenames {
+				if input.GetText() == filename {
+					input.SetText(defaultFilenames[option])
+					break
+				}
+			}
+		})
+	}
+
+	// Save button
+	saveAction := func() {
+		form := tview.NewForm()
+		addFormatFields(form, "JSON", "Binary", "YAML")
+		form.AddButton("Save", func() {
+			filename := form.GetFormItem(0).(*tview.InputField).GetText()
+			var err error
+			switch _, format := form.GetFormItem(1).(*tview.DropDown).GetCurrentOption(); format {
+			case "Binary":
+				err = manager.SaveToGobFiltered(filename, exportFilters[exportFilter])
+			case "YAML":
+				err = manager.SaveToYAMLFiltered(filename, exportFilters[exportFilter])
+			default:
+				err = manager.SaveToFileFiltered(filename, exportFilters[exportFilter])
+			}
+			var modalText string
+			if err != nil {
+				modalText = fmt.Sprintf("Error saving: %v", err)
+			} else {
+				modalText = fmt.Sprintf("Successfully saved to %s", filename)
+			}
+
+			modal := tview.NewModal().
+				SetText(modalText).
+				AddButtons([]string{"OK"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					app.SetRoot(grid, true)
+				})
+			app.SetRoot(modal, false)
+		})
+		form.AddButton("Cancel", func() {
+			app.SetRoot(grid, true)
+		})
+		form.SetBorder(true).SetTitle("Save Timers")
+		form.SetCancelFunc(func() {
+			app.SetRoot(grid, true)
+		})
+		app.SetRoot(form, true)
+	}
+	saveButton := tview.NewButton("Save").SetSelectedFunc(saveAction)
+
+	// Load button
+	// loadFile loads filename in the format named as in the load dialog and
+	// reports the outcome in a modal
+	loadFile := func(filename, format string) {
+		var err error
+		switch format {
+		case "Binary":
+			err = manager.LoadFromGob(filename)
+		case "YAML":
+			err = manager.LoadFromYAML(filename)
+		case "CSV":
+			err = manager.LoadFromCSV(filename)
+		default:
+			err = manager.LoadFromFile(filename, *resumeRunning)
+		}
+		var modalText string
+		if err != nil {
+			modalText = fmt.Sprintf("Error loading: %v", err)
+		} else {
+			modalText = fmt.Sprintf("Successfully loaded from %s", filename)
+			if warnings := manager.LoadWarnings(); len(warnings) > 0 {
+				modalText += "\n\nWarnings:\n" + strings.Join(warnings, "\n")
+			}
+			// Update the UI with the loaded values
+			for _, view := range views {
+				view.Refresh()
+			}
+		}
+
+		modal := tview.NewModal().
+			SetText(modalText).
+			AddButtons([]string{"OK"}).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				app.SetRoot(grid, true)
+				if err == nil {
+					focusView(manager.Current())
+				}
+			})
+		app.SetRoot(modal, false)
+	}
+
+	// recentAction lists the saves in the save directory, newest first;
+	// selecting one loads it and Esc returns to back
+	recentAction := func(back tview.Primitive) {
+		dir := manager.SaveDir()
+		files, err := ListSaveFiles(dir)
+		if err != nil || len(files) == 0 {
+			text := fmt.Sprintf("No JSON, YAML or CSV saves in %s", dir)
+			if err != nil {
+				text = fmt.Sprintf("Error listing saves: %v", err)
+			}
+			modal := tview.NewModal().
+				SetText(text).
+				AddButtons([]string{"OK"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					app.SetRoot(back, true)
+				})
+			app.SetRoot(modal, false)
+			return
+		}
+
+		list := tview.NewList()
+		for _, file := range files {
+			file := file
+			timers := strconv.Itoa(file.Timers)
+			if file.MoreTimers {
+				timers += "+"
+			}
+			detail := fmt.Sprintf("%s, %s timers, saved %s", file.Format, timers, file.SaveTime.Local().Format("2006-01-02 15:04:05"))
+			if file.SaveTime.IsZero() {
+				detail = fmt.Sprintf("%s, %s timers, modified %s", file.Format, timers, file.ModTime.Format("2006-01-02 15:04:05"))
+			}
+			list.AddItem(tview.Escape(file.Name), detail, 0, func() {
+				loadFile(file.Name, file.Format)
+			})
+		}
+		list.SetDoneFunc(func() {
+			app.SetRoot(back, true)
+		})
+		list.SetBorder(true).SetTitle(fmt.Sprintf(" Saves in %s (Enter: load, Esc: back) ", tview.Escape(dir)))
+		app.SetRoot(list, true)
+	}
+
+	loadAction := func() {
+		form := tview.NewForm()
+		addFormatFields(form, "JSON", "Binary", "YAML", "CSV")
+		form.AddButton("Load", func() {
+			filename := form.GetFormItem(0).(*tview.InputField).GetText()
+			_, format := form.GetFormItem(1).(*tview.DropDown).GetCurrentOption()
+			loadFile(filename, format)
+		})
+		form.AddButton("Recent...", func() {
+			recentAction(form)
+		})
+		form.AddButton("Cancel", func() {
+			app.SetRoot(grid, true)
+		})
+		form.SetBorder(true).SetTitle("Load Timers")
+		form.SetCancelFunc(func() {
+			app.SetRoot(grid, true)
+		})
+		app.SetRoot(form, true)
+	}
+	loadButton := tview.NewButton("Load").SetSelectedFunc(loadAction)
+
+	// Export button, writing a CSV or a flat JSON array for other tools
+	exportAction := func() {
+		form := tview.NewForm()
+		form.AddInputField("Filename", defaultFilenames["CSV"], 20, nil, nil)
+		form.AddDropDown("Format", []string{"CSV", "JSON"}, 0, func(option string, index int) {
+			input := form.GetFormItem(0).(*tview.InputField)
+			if text := input.GetText(); text == defaultFilenames["CSV"] || text == defaultFilenames["JSON"] {
+				input.SetText(defaultFilenames[option])
+			}
+		})
+		form.AddButton("Export", func() {
+			filename := form.GetFormItem(0).(*tview.InputField).GetText()
+			var err error
+			switch _, format := form.GetFormItem(1).(*tview.DropDown).GetCurrentOption(); format {
+			case "JSON":
+				err = manager.ExportJSONFiltered(filename, exportFilters[exportFilter])
+			default:
+				err = manager.SaveToCSVFiltered(filename, exportFilters[exportFilter])
+			}
+			var modalText string
+			if err != nil {
+				modalText = fmt.Sprintf("Error exporting: %v", err)
+			} else {
+				modalText = fmt.Sprintf("Successfully exported to %s", filename)
+			}
+
+			modal := tview.NewModal().
+				SetText(modalText).
+				AddButtons([]string{"OK"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					app.SetRoot(grid, true)
+				})
+			app.SetRoot(modal, false)
+		})
+		form.AddButton("Cancel", func() {
+			app.SetRoot(grid, true)
+		})
+		form.SetBorder(true).SetTitle("Export Timers")
+		form.SetCancelFunc(func() {
+			app.SetRoot(grid, true)
+		})
+		app.SetRoot(form, true)
+	}
+	exportButton := tview.NewButton("Export").SetSelectedFunc(exportAction)
+
+	// Export ICS button, writing each recorded run as a calendar event
+	icsAction := func() {
+		form := tview.NewForm()
+		form.AddInputField("Filename", "timers.ics", 20, nil, nil)
+		form.AddButton("Export", func() {
+			filename := form.GetFormItem(0).(*tview.InputField).GetText()
+			err := manager.SaveToICSFiltered(filename, exportFilters[exportFilter])
+			var modalText string
+			if err != nil {
+				modalText = fmt.Sprintf("Error exporting: %v", err)
+			} else {
+				modalText = fmt.Sprintf("Successfully exported to %s", filename)
+			}
+
+			modal := tview.NewModal().
+				SetText(modalText).
+				AddButtons([]string{"OK"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					app.SetRoot(grid, true)
+				})
+			app.SetRoot(modal, false)
+		})
+		form.AddButton("Cancel", func() {
+			app.SetRoot(grid, true)
+		})
+		form.SetBorder(true).SetTitle("Export to iCalendar")
+		form.SetCancelFunc(func() {
+			app.SetRoot(grid, true)
+		})
+		app.SetRoot(form, true)
+	}
+	icsButton := tview.NewButton("Export ICS").SetSelectedFunc(icsAction)
+
+	// Export MD button, writing the Markdown summary table
+	markdownAction := func() {
+		form := tview.NewForm()
+		form.AddInputField("Filename", "timers.md", 20, nil, nil)
+		form.AddButton("Export", func() {
+			filename := form.GetFormItem(0).(*tview.InputField).GetText()
+			err := manager.ExportMarkdown(filename)
+			var modalText string
+			if err != nil {
+				modalText = fmt.Sprintf("Error exporting: %v", err)
+			} else {
+				modalText = fmt.Sprintf("Successfully exported to %s", filename)
+			}
+
+			modal := tview.NewModal().
+				SetText(modalText).
+				AddButtons([]string{"OK"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					app.SetRoot(grid, true)
+				})
+			app.SetRoot(modal, false)
+		})
+		form.AddButton("Cancel", func() {
+			app.SetRoot(grid, true)
+		})
+		form.SetBorder(true).SetTitle("Export to Markdown")
+		form.SetCancelFunc(func() {
+			app.SetRoot(grid, true)
+		})
+		app.SetRoot(form, true)
+	}
+	markdownButton := tview.NewButton("Export MD").SetSelectedFunc(markdownAction)
+
+	// Export Chart button, rendering a bar chart of the elapsed times
+	chartAction := func() {
+		form := tview.NewForm()
+		form.AddInputField("Filename", "timers.png", 20, nil, nil)
+		form.AddButton("Export", func() {
+			filename := form.GetFormItem(0).(*tview.InputField).GetText()
+			err := manager.SavePlotFiltered(filename, exportFilters[exportFilter])
+			var modalText string
+			if err != nil {
+				modalText = fmt.Sprintf("Error exporting: %v", err)
+			} else {
+				modalText = fmt.Sprintf("Successfully exported to %s", filename)
+			}
+
+			modal := tview.NewModal().
+				SetText(modalText).
+				AddButtons([]string{"OK"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					app.SetRoot(grid, true)
+				})
+			app.SetRoot(modal, false)
+		})
+		form.AddButton("Cancel", func() {
+			app.SetRoot(grid, true)
+		})
+		form.SetBorder(true).SetTitle("Export Chart")
+		form.SetCancelFunc(func() {
+			app.SetRoot(grid, true)
+		})
+		app.SetRoot(form, true)
+	}
+	chartButton := tview.NewButton("Export Chart").SetSelectedFunc(chartAction)
+
+	// Laps CSV button, exporting or importing one row per lap
+	lapsAction := func() {
+		form := tview.NewForm()
+		form.AddInputField("Filename", "laps.csv", 20, nil, nil)
+		showResult := func(modalText string) {
+			modal := tview.NewModal().
+				SetText(modalText).
+				AddButtons([]string{"OK"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					app.SetRoot(grid, true)
+				})
+			app.SetRoot(modal, false)
+		}
+		form.AddButton("Export", func() {
+			filename := form.GetFormItem(0).(*tview.InputField).GetText()
+			if err := manager.SaveLapsToCSV(filename); err != nil {
+				showResult(fmt.Sprintf("Error exporting laps: %v", err))
+			} else {
+				showResult(fmt.Sprintf("Successfully exported laps to %s", filename))
+			}
+		})
+		form.AddButton("Import", func() {
+			filename := form.GetFormItem(0).(*tview.InputField).GetText()
+			if err := manager.LoadLapsFromCSV(filename); err != nil {
+				showResult(fmt.Sprintf("Error importing laps: %v", err))
+				return
+			}
+			modalText := fmt.Sprintf("Successfully imported laps from %s", filename)
+			if warnings := manager.LoadWarnings(); len(warnings) > 0 {
+				modalText += "\n\nWarnings:\n" + strings.Join(warnings, "\n")
+			}
+			showResult(modalText)
+		})
+		form.AddButton("Cancel", func() {
+			app.SetRoot(grid, true)
+		})
+		form.SetBorder(true).SetTitle("Laps CSV")
+		form.SetCancelFunc(func() {
+			app.SetRoot(grid, true)
+		})
+		app.SetRoot(form, true)
+	}
+	lapsButton := tview.NewButton("Laps CSV").SetSelectedFunc(lapsAction)
+
+	// compareAction asks for two save files and shows their timers side by
+	// side, matched by ID, with the change in elapsed time. Unchanged timers
+	// are grayed out.
+	compareAction := func() {
+		form := tview.NewForm()
+		form.AddInputField("First save", "timers.json", 30, nil, nil)
+		form.AddInputField("Second save", "", 30, nil, nil)
+		form.AddTextView("", "", 40, 1, true, false)
+		form.AddButton("Compare", func() {
+			fileA := form.GetFormItem(0).(*tview.InputField).GetText()
+			fileB := form.GetFormItem(1).(*tview.InputField).GetText()
+			diffs, err := CompareSaves(manager.resolvePath(fileA), manager.resolvePath(fileB))
+			if err != nil {
+				form.GetFormItem(2).(*tview.TextView).SetText("[red]" + tview.Escape(err.Error()))
+				return
+			}
+
+			table := tview.NewTable().SetFixed(1, 0)
+			for col, heading := range []string{"ID", fileA, fileB, "Before", "After", "Change"} {
+				table.SetCell(0, col, tview.NewTableCell(tview.Escape(heading)).
+					SetAttributes(tcell.AttrBold).
+					SetSelectable(false))
+			}
+			for row, d := range diffs {
+				cells := []string{strconv.Itoa(d.ID), d.LabelA, d.LabelB,
+					formatDuration(d.ElapsedA), formatDuration(d.ElapsedB), formatDelta(d.Delta)}
+				if !d.InA {
+					cells[1], cells[3] = "-", "-"
+				}
+				if !d.InB {
+					cells[2], cells[4] = "-", "-"
+				}
+				color := tcell.GetColor(theme.Text)
+				if d.InA && d.InB && d.LabelA == d.LabelB && d.Delta == 0 {
+					color = tcell.ColorGray
+				}
+				for col, text := range cells {
+					table.SetCell(row+1, col, tview.NewTableCell(tview.Escape(text)).SetTextColor(color))
+				}
+			}
+			table.SetBorder(true).SetTitle(" Compare saves (Esc: close) ")
+			table.SetDoneFunc(func(key tcell.Key) {
+				app.SetRoot(grid, true)
+			})
+			app.SetRoot(table, true)
+		})
+		form.AddButton("Cancel", func() {
+			app.SetRoot(grid, true)
+		})
+		form.SetBorder(true).SetTitle("Compare Saves")
+		form.SetCancelFunc(func() {
+			app.SetRoot(grid, true)
+		})
+		app.SetRoot(form, true)
+	}
+
+	// Export filter toggle, cycling through all/running/selected
+	filterButton := tview.NewButton(fmt.Sprintf("Filter: %s", exportFilter))
+	filterAction := func() {
+		for i, name := range exportFilterNames {
+			if name == exportFilter {
+				exportFilter = exportFilterNames[(i+1)%len(exportFilterNames)]
+				break
+			}
+		}
+		filterButton.SetLabel(fmt.Sprintf("Filter: %s", exportFilter))
+	}
+	filterButton.SetSelectedFunc(filterAction)
+
+	// Reset labels button, asking for confirmation first
+	resetLabelsAction := func() {
+		modal := tview.NewModal().
+			SetText("Restore the default label of every timer? Elapsed times are kept.").
+			AddButtons([]string{"Reset Labels", "Cancel"}).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				if buttonLabel == "Reset Labels" {
+					manager.ResetLabels()
+					for _, view := range views {
+						view.Refresh()
+					}
+					logEvent("Labels reset to their defaults")
+				}
+				app.SetRoot(grid, true)
+			})
+		app.SetRoot(modal, false)
+	}
+	resetLabelsButton := tview.NewButton("Reset Labels").SetSelectedFunc(resetLabelsAction)
+
+	// Bulk button, setting the same targets on every selected timer. Fields
+	// left empty keep each timer's current value.
+	bulkAction := func() {
+		ids := manager.SelectedIDs()
+		if len(ids) == 0 {
+			logEvent("No timers selected: press space on a timer to select it")
+			return
+		}
+		form := tview.NewForm()
+		form.AddInputField("Count down from (e.g. 25m)", "", 20, nil, nil)
+		form.AddInputField("Budget (e.g. 1h30m)", "", 20, nil, nil)
+		form.AddInputField("Overtime at (e.g. 30m)", "", 20, nil, nil)
+		form.AddTextView("", "", 40, 1, true, false)
+		form.AddButton("Set", func() {
+			setters := []func(*Chronometer, time.Duration){
+				(*Chronometer).SetTarget,
+				(*Chronometer).SetBudget,
+				(*Chronometer).SetOvertimeAt,
+			}
+			var apply []func(*Chronometer)
+			for n, set := range setters {
+				text := strings.TrimSpace(form.GetFormItem(n).(*tview.InputField).GetText())
+				if text == "" {
+					continue
+				}
+				d, err := parseHuman(text)
+				if err != nil {
+					form.GetFormItem(len(setters)).(*tview.TextView).SetText("[red]" + tview.Escape(err.Error()))
+					return
+				}
+				set := set
+				apply = append(apply, func(c *Chronometer) { set(c, d) })
+			}
+			manager.BulkSet(ids, func(c *Chronometer) {
+				for _, fn := range apply {
+					fn(c)
+				}
+			})
+			logEvent("Updated %d selected timers", len(ids))
+			app.SetRoot(grid, true)
+		})
+		form.AddButton("Cancel", func() {
+			app.SetRoot(grid, true)
+		})
+		form.SetBorder(true).SetTitle(fmt.Sprintf("Set %d Selected Timers", len(ids)))
+		form.SetCancelFunc(func() {
+			app.SetRoot(grid, true)
+		})
+		app.SetRoot(form, true)
+	}
+	bulkButton := tview.NewButton("Bulk: 0 selected").SetSelectedFunc(bulkAction)
+
+	// Batch buttons acting on every timer at once
+	startAllButton := tview.NewButton("Start All").SetSelectedFunc(func() {
+		if !manager.StartAll() {
+			logEvent("Start All needs concurrent mode")
+		}
+	})
+	stopAllButton := tview.NewButton("Stop All").SetSelectedFunc(manager.StopAll)
+	resetAllButton := tview.NewButton("Reset All").SetSelectedFunc(manager.ResetAll)
+
+	// shutdown saves the timers to -recovery-file, if set, and stops the
+	// application. The outcome is reported once the terminal is restored.
+	// Run only returns after the save, so it completes before the process
+	// exits even when a signal triggered it.
+	shutdownReport := make(chan string, 1)
+	shutdown := func() {
+		if *recoveryFile != "" {
+			report := fmt.Sprintf("Timers saved to %s; they will be offered for restore at the next start", manager.resolvePath(*recoveryFile))
+			if err := manager.SaveToFile(*recoveryFile); err != nil {
+				report = fmt.Sprintf("Error saving %s: %v", *recoveryFile, err)
+			}
+			select {
+			case shutdownReport <- report:
+			default:
+			}
+		}
+		app.Stop()
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		shutdown()
+	}()
+
+	// Quit button
+	quitAction := func() {
+		buttons := []string{"Quit", "Cancel"}
+		if *recoveryFile != "" {
+			buttons = []string{"Save & Quit", "Quit", "Cancel"}
+		}
+		modal := tview.NewModal().
+			SetText("Are you sure you want to quit?").
+			AddButtons(buttons).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				switch buttonLabel {
+				case "Save & Quit":
+					shutdown()
+				case "Quit":
+					app.Stop()
+				default:
+					app.SetRoot(grid, true)
+				}
+			})
+		app.SetRoot(modal, false)
+	}
+	quitButton := tview.NewButton("Quit").SetSelectedFunc(quitAction)
+
+	buttonPanel.AddItem(saveButton, 0, 1, false)
+	buttonPanel.AddItem(loadButton, 0, 1, false)
+	buttonPanel.AddItem(exportButton, 0, 1, false)
+	buttonPanel.AddItem(icsButton, 0, 1, false)
+	buttonPanel.AddItem(chartButton, 0, 1, false)
+	buttonPanel.AddItem(markdownButton, 0, 1, false)
+	buttonPanel.AddItem(lapsButton, 0, 1, false)
+	buttonPanel.AddItem(filterButton, 0, 1, false)
+	buttonPanel.AddItem(resetLabelsButton, 0, 1, false)
+	buttonPanel.AddItem(bulkButton, 0, 1, false)
+	buttonPanel.AddItem(startAllButton, 0, 1, false)
+	buttonPanel.AddItem(stopAllButton, 0, 1, false)
+	buttonPanel.AddItem(resetAllButton, 0, 1, false)
+	buttonPanel.AddItem(quitButton, 0, 1, false)
+
+	// Add chronometers and button panel to main grid
+	grid.AddItem(titleBar, 0, 0, 1, 1, 0, 0, false)
+	grid.AddItem(timersPane, 1, 0, 1, 1, 0, 0, true)
+	grid.AddItem(totalLine, 2, 0, 1, 1, 0, 0, false)
+	grid.AddItem(eventLog, 3, 0, 1, 1, 0, 0, false)
+	grid.AddItem(buttonPanel, 4, 0, 1, 1, 0, 0, false)
+
+	// toggleButtonPanel hides or shows the bottom button panel, giving its
+	// rows to the timers. Focus is moved off the panel before it disappears.
+	panelHidden := false
+	toggleButtonPanel := func() {
+		panelHidden = !panelHidden
+		if panelHidden {
+			if buttonPanel.HasFocus() {
+				app.SetFocus(chronoGrid)
+			}
+			grid.RemoveItem(buttonPanel)
+			grid.SetRows(1, 0, 1, 1)
+		} else {
+			grid.SetRows(1, 0, 1, 1, 3)
+			grid.AddItem(buttonPanel, 4, 0, 1, 1, 0, 0, false)
+		}
+	}
+
+	// Leaderboard view ranking the finished timers, fastest first. It is
+	// refreshed by the display loop while open, so stopped timers show up
+	// right away; a toggles whether running timers are ranked too.
+	leaderboardView := tview.NewTextView().SetDynamicColors(true)
+	leaderboardView.SetBorder(true)
+	leaderboardOpen, leaderboardLive := false, false
+	updateLeaderboard := func() {
+		entries := manager.Leaderboard()
+		title := " Leaderboard (a: include running, Esc: close) "
+		if leaderboardLive {
+			entries = manager.LiveLeaderboard()
+			title = " Leaderboard incl. running (a: finished only, Esc: close) "
+		}
+		leaderboardView.SetTitle(title)
+		var b strings.Builder
+		b.WriteString("[-]Rank  Time          Label\n")
+		for n, cd := range entries {
+			color := theme.Time
+			if cd.IsRunning {
+				color = "gray"
+			}
+			fmt.Fprintf(&b, "[%s]%4d  %s  %s\n", color, n+1,
+				formatDurationPrecision(cd.ElapsedTime, precisionDigits[precision]), tview.Escape(cd.DisplayLabel))
+		}
+		if len(entries) == 0 {
+			b.WriteString("[gray]No finished timers yet\n")
+		}
+		leaderboardView.SetText(b.String())
+	}
+	leaderboardView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEsc || event.Rune() == 'q':
+			leaderboardOpen = false
+			app.SetRoot(grid, true)
+			return nil
+		case event.Rune() == 'a':
+			leaderboardLive = !leaderboardLive
+			updateLeaderboard()
+			return nil
+		}
+		return event
+	})
+	leaderboardAction := func() {
+		leaderboardOpen = true
+		updateLeaderboard()
+		app.SetRoot(leaderboardView, true)
+	}
+
+	// The screen, for ringing the bell; tview only hands it out to draw
+	// callbacks
+	var screen tcell.Screen
+	app.SetBeforeDrawFunc(func(s tcell.Screen) bool {
+		screen = s
+		return false
+	})
+
+	// announceFinished reports a countdown that ran out, or for a Pomodoro
+	// timer the phase it moved on to. It rings the bell and flashes the
+	// timer's border for a second.
+	announceFinished := func(c *Chronometer) {
+		if screen != nil {
+			screen.Beep()
+		}
+		for _, view := range views {
+			if view.ChronoID() == c.id {
+				view.Flash(time.Second)
+			}
+		}
+		message := fmt.Sprintf("Countdown finished: %s", c.displayLabel)
+		if c.maxDuration > 0 && c.elapsedTime == c.maxDuration {
+			message = fmt.Sprintf("Stopped at its maximum of %s: %s", formatDuration(c.maxDuration), c.displayLabel)
+		}
+		switch c.pomodoroPhase {
+		case PomodoroBreak:
+			message = fmt.Sprintf("Pomodoro %d done: %s, take a break", c.pomodoros, c.displayLabel)
+		case PomodoroWork:
+			message = fmt.Sprintf("Break over: %s, back to work", c.displayLabel)
+		}
+		logEvent("%s", message)
+		if !*desktopNotifyFlag {
+			return
+		}
+		go func() {
+			if err := desktopNotify("metrochrono", message); err != nil {
+				app.QueueUpdateDraw(func() {
+					logEvent("Desktop notification failed: %v", err)
 				})
-			app.SetRoot(modal, false)
+			}
+		}()
+	}
+	manager.OnFinish(func(c *Chronometer) {
+		app.QueueUpdateDraw(func() { announceFinished(c) })
+	})
+
+	// requestRedraw wakes the display loop below to redraw the timers now
+	// rather than at its next tick. Requests made while one is pending are
+	// folded into it.
+	redraw := make(chan struct{}, 1)
+	requestRedraw := func() {
+		select {
+		case redraw <- struct{}{}:
+		default:
+		}
+	}
+
+	// Starts, stops and resets go to the event log. They can come from the
+	// event loop itself, so they are queued here and logged by the display
+	// loop rather than through QueueUpdateDraw, which could block it.
+	var (
+		transitionsMu sync.Mutex
+		transitions   []string
+	)
+	manager.OnStateChange(func(id int, event string, at time.Time) {
+		transitionsMu.Lock()
+		defer transitionsMu.Unlock()
+
+		transitions = append(transitions, fmt.Sprintf("Timer %d: %s", id, event))
+		requestRedraw()
+	})
+	// The title bar shows the time or error of the last autosave
+	manager.OnAutoSave(func(error) { requestRedraw() })
+	logTransitions := func() {
+		transitionsMu.Lock()
+		pending := transitions
+		transitions = nil
+		transitionsMu.Unlock()
+
+		for _, line := range pending {
+			logEvent("%s", line)
+		}
+	}
+
+	// With -reset-at, reset all timers once a day. The next occurrence is
+	// recomputed after each reset so DST changes never shift or repeat it,
+	// and starting after today's time waits for tomorrow's.
+	if *resetAt != "" {
+		go func() {
+			for {
+				next := nextDailyAt(time.Now(), resetHour, resetMinute)
+				time.Sleep(time.Until(next))
+
+				var saveErr error
+				saved := ""
+				if *resetSave != "" {
+					saved = datedFilename(*resetSave, next.AddDate(0, 0, -1))
+					saveErr = manager.SaveToFile(saved)
+				}
+				manager.ResetAll()
+				app.QueueUpdateDraw(func() {
+					if saveErr != nil {
+						logEvent("Error saving before scheduled reset: %v", saveErr)
+					} else if saved != "" {
+						logEvent("Saved %s before scheduled reset", saved)
+					}
+					logEvent("Scheduled reset of all timers at %s", next.Format("15:04"))
+				})
+			}
+		}()
+	}
+
+	// Start timers whose delayed start has come, stop countdowns that ran
+	// out, which calls the OnFinish callbacks, and stop timers left idle
+	go func() {
+		for {
+			time.Sleep(100 * time.Millisecond)
+			manager.StopFinished(time.Now())
+			if stopped := manager.StopIdle(time.Now()); len(stopped) > 0 {
+				app.QueueUpdateDraw(func() {
+					for _, id := range stopped {
+						c, _ := manager.copyOf(id)
+						logEvent("Stopped %s: untouched for %s", c.displayLabel, *idleStop)
+					}
+				})
+			}
+			if started := manager.StartDue(time.Now()); len(started) > 0 {
+				app.QueueUpdateDraw(func() {
+					for _, id := range started {
+						c, _ := manager.copyOf(id)
+						logEvent("Scheduled start of %s", c.displayLabel)
+					}
+				})
+			}
+		}
+	}()
+
+	// Redraw the timers at the refresh interval while one is running, and
+	// while none is once a second if a countdown to a scheduled start is
+	// shown. Otherwise only changes, which signal requestRedraw, redraw.
+	var tick atomic.Int64
+	go func() {
+		for {
+			var wake <-chan time.Time
+			if d := time.Duration(tick.Load()); d > 0 {
+				wake = time.After(d)
+			}
+			select {
+			case <-redraw:
+			case <-wake:
+			}
+			app.QueueUpdate(func() {
+				logTransitions()
+				_, editing := app.GetFocus().(*tview.InputField)
+				running := manager.RunningCount() > 0
+				style := TimerViewStyle{
+					Digits:      precisionDigits[precision],
+					DayCounter:  prefs.DayCounter,
+					Indicator:   statusIndicators[manager.indicators].themed(theme),
+					Theme:       theme,
+					Dense:       dense,
+					TitleLabels: prefs.TitleLabels,
+					Dim:         !running && *idleIndicator == "dim",
+				}
+				switch {
+				case running:
+					tick.Store(int64(refreshInterval(style.Digits)))
+				case len(manager.Find(func(cd ChronoData) bool { return !cd.ScheduledAt.IsZero() })) > 0:
+					tick.Store(int64(time.Second))
+				default:
+					tick.Store(0)
+				}
+
+				if *idleIndicator == "banner" {
+					chronoGrid.SetBorder(!running)
+				}
+
+				for _, view := range views {
+					view.Update(style, editing)
+				}
+				totalLine.SetText("[-]Total: " + colorTag(theme.Time) + formatDurationWithDaysPrecision(manager.TotalElapsed(), style.Digits))
+
+				bulkButton.SetLabel(fmt.Sprintf("Bulk: %d selected", len(manager.SelectedIDs())))
+				updateTitleBar()
+				if leaderboardOpen {
+					updateLeaderboard()
+				}
+				app.ForceDraw()
+			})
+		}
+	}()
+	requestRedraw()
+
+	// focusedTimer returns the index of the chronometer whose widgets have
+	// focus, or -1 if focus is elsewhere
+	focusedTimer := func() int {
+		for i, view := range views {
+			if view.HasFocus() {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// removeAction asks before removing the timer, which is stopped first
+	removeAction := func(id int) {
+		c, _ := manager.copyOf(id)
+		label := c.displayLabel
+		modal := tview.NewModal().
+			SetText(fmt.Sprintf("Remove %s?", label)).
+			AddButtons([]string{"Remove", "Cancel"}).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				if buttonLabel == "Remove" {
+					if err := manager.RemoveChronometer(c.id); err != nil {
+						logEvent("Error removing timer: %v", err)
+					} else {
+						layoutTimers()
+						logEvent("Removed %s", label)
+					}
+				}
+				app.SetRoot(grid, true)
+			})
+		app.SetRoot(modal, false)
+	}
+
+	// mergeAction asks for two timer IDs and merges the first into the
+	// second, offering the timer focused last as the source
+	mergeAction := func() {
+		current, _ := manager.copyOf(manager.Current())
+		form := tview.NewForm()
+		form.AddInputField("Merge timer", strconv.Itoa(current.id), 6, nil, nil)
+		form.AddInputField("Into timer", "", 6, nil, nil)
+		form.AddTextView("", "", 40, 1, true, false)
+		form.AddButton("Merge", func() {
+			var ids [2]int
+			for n := range ids {
+				text := strings.TrimSpace(form.GetFormItem(n).(*tview.InputField).GetText())
+				number, err := strconv.Atoi(text)
+				index, ok := manager.IndexOf(number)
+				if err != nil || !ok {
+					form.GetFormItem(2).(*tview.TextView).SetText("[red]" + tview.Escape(fmt.Sprintf("no timer %q", text)))
+					return
+				}
+				ids[n] = index
+			}
+			src, _ := manager.copyOf(ids[0])
+			dst, _ := manager.copyOf(ids[1])
+			if err := manager.MergeTimers(ids[0], ids[1]); err != nil {
+				form.GetFormItem(2).(*tview.TextView).SetText("[red]" + tview.Escape(err.Error()))
+				return
+			}
+			logEvent("Merged %s into %s", src.displayLabel, dst.displayLabel)
+			app.SetRoot(grid, true)
 		})
 		form.AddButton("Cancel", func() {
 			app.SetRoot(grid, true)
 		})
-		form.SetBorder(true).SetTitle("Save Timers")
+		form.SetBorder(true).SetTitle("Merge Timers")
 		form.SetCancelFunc(func() {
 			app.SetRoot(grid, true)
 		})
 		app.SetRoot(form, true)
-	})
+	}
 
-	// Load button
-	loadButton := tview.NewButton("Load").SetSelectedFunc(func() {
+	// scheduleAction asks when the timer should start by itself; an empty
+	// entry cancels a pending start
+	scheduleAction := func(id int) {
+		c, _ := manager.copyOf(id)
+		current := ""
+		if !c.scheduledAt.IsZero() {
+			current = c.scheduledAt.Format("15:04")
+		}
 		form := tview.NewForm()
-		form.AddInputField("Filename", "timers.json", 20, nil, nil)
-		form.AddButton("Load", func() {
-			filename := form.GetFormItem(0).(*tview.InputField).GetText()
-			err := manager.LoadFromFile(filename)
-			var modalText string
+		form.AddInputField("Start at (HH:MM or e.g. 10m)", current, 20, nil, nil)
+		form.AddTextView("", "", 40, 1, true, false)
+		form.AddButton("Set", func() {
+			text := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+			if text == "" {
+				manager.ScheduleStart(id, time.Time{})
+				logEvent("Scheduled start of Timer %d cancelled", c.id)
+				app.SetRoot(grid, true)
+				return
+			}
+			at, err := parseStartAt(text, time.Now())
 			if err != nil {
-				modalText = fmt.Sprintf("Error loading: %v", err)
-			} else {
-				modalText = fmt.Sprintf("Successfully loaded from %s", filename)
-				// Update the UI with the loaded values
-				for i, c := range manager.chronometers {
-					labelInputs[i].SetText(c.displayLabel)
-				}
+				form.GetFormItem(1).(*tview.TextView).SetText("[red]" + tview.Escape(err.Error()))
+				return
 			}
-
-			modal := tview.NewModal().
-				SetText(modalText).
-				AddButtons([]string{"OK"}).
-				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-					app.SetRoot(grid, true)
-				})
-			app.SetRoot(modal, false)
+			manager.ScheduleStart(id, at)
+			logEvent("Timer %d will start at %s", c.id, at.Format("15:04:05"))
+			app.SetRoot(grid, true)
 		})
 		form.AddButton("Cancel", func() {
 			app.SetRoot(grid, true)
 		})
-		form.SetBorder(true).SetTitle("Load Timers")
+		form.SetBorder(true).SetTitle(fmt.Sprintf("Delayed Start for Timer %d", c.id))
 		form.SetCancelFunc(func() {
 			app.SetRoot(grid, true)
 		})
 		app.SetRoot(form, true)
-	})
+	}
 
-	// Export CSV button
-	exportButton := tview.NewButton("Export CSV").SetSelectedFunc(func() {
+	// categoryAction asks for the category of the timer; an empty entry
+	// removes it
+	categoryAction := func(id int) {
+		c, _ := manager.copyOf(id)
 		form := tview.NewForm()
-		form.AddInputField("Filename", "timers.csv", 20, nil, nil)
-		form.AddButton("Export", func() {
-			filename := form.GetFormItem(0).(*tview.InputField).GetText()
-			err := manager.SaveToCSV(filename)
-			var modalText string
-			if err != nil {
-				modalText = fmt.Sprintf("Error exporting: %v", err)
-			} else {
-				modalText = fmt.Sprintf("Successfully exported to %s", filename)
+		form.AddInputField("Category", c.category, 20, nil, nil)
+		form.AddButton("Set", func() {
+			category := form.GetFormItem(0).(*tview.InputField).GetText()
+			if err := manager.SetCategory(id, category); err != nil {
+				logEvent("Error setting category: %v", err)
 			}
-
-			modal := tview.NewModal().
-				SetText(modalText).
-				AddButtons([]string{"OK"}).
-				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-					app.SetRoot(grid, true)
-				})
-			app.SetRoot(modal, false)
+			app.SetRoot(grid, true)
 		})
 		form.AddButton("Cancel", func() {
 			app.SetRoot(grid, true)
 		})
-		form.SetBorder(true).SetTitle("Export to CSV")
+		form.SetBorder(true).SetTitle(fmt.Sprintf("Category for Timer %d", c.id))
 		form.SetCancelFunc(func() {
 			app.SetRoot(grid, true)
 		})
 		app.SetRoot(form, true)
-	})
+	}
 
-	// Quit button
-	quitButton := tview.NewButton("Quit").SetSelectedFunc(func() {
+	// sharesAction shows where the recorded time went: the timers with the
+	// largest share of the total, largest first, then the total of each
+	// category
+	sharesAction := func() {
+		shares := manager.Shares()
+		ids := make([]int, 0, len(shares))
+		for id, share := range shares {
+			if share > 0 {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			if shares[ids[i]] != shares[ids[j]] {
+				return shares[ids[i]] > shares[ids[j]]
+			}
+			return ids[i] < ids[j]
+		})
+		const top = 5
+		if len(ids) > top {
+			ids = ids[:top]
+		}
+
+		text := fmt.Sprintf("Total: %s\n\n", formatDuration(manager.TotalElapsed()))
+		for _, id := range ids {
+			c, _ := manager.copyOf(id)
+			text += fmt.Sprintf("%3.0f%%  %s\n", shares[id]*100, c.displayLabel)
+		}
+		if len(ids) == 0 {
+			text += "No time recorded yet"
+		}
+		totals := manager.TotalByCategory()
+		categories := make([]string, 0, len(totals))
+		for category := range totals {
+			if category != "" {
+				categories = append(categories, category)
+			}
+		}
+		sort.Strings(categories)
+		if len(categories) > 0 {
+			text += "\nBy category:\n"
+			for _, category := range categories {
+				text += fmt.Sprintf("%s  %s\n", formatDuration(totals[category]), category)
+			}
+			if uncategorized, ok := totals[""]; ok {
+				text += fmt.Sprintf("%s  (none)\n", formatDuration(uncategorized))
+			}
+		}
 		modal := tview.NewModal().
-			SetText("Are you sure you want to quit?").
-			AddButtons([]string{"Quit", "Cancel"}).
+			SetText(text).
+			AddButtons([]string{"OK"}).
 			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-				if buttonLabel == "Quit" {
-					app.Stop()
-				} else {
-					app.SetRoot(grid, true)
-				}
+				app.SetRoot(grid, true)
 			})
 		app.SetRoot(modal, false)
-	})
-
-	buttonPanel.AddItem(saveButton, 0, 1, false)
-	buttonPanel.AddItem(loadButton, 0, 1, false)
-	buttonPanel.AddItem(exportButton, 0, 1, false)
-	buttonPanel.AddItem(quitButton, 0, 1, false)
-
-	// Add chronometers and button panel to main grid
-	grid.AddItem(chronoGrid, 0, 0, 1, 1, 0, 0, true)
-	grid.AddItem(buttonPanel, 1, 0, 1, 1, 0, 0, false)
+	}
 
-	// Update the timer displays every 10 milliseconds
-	go func() {
-		for {
-			time.Sleep(10 * time.Millisecond)
-			app.QueueUpdateDraw(func() {
-				for i, c := range manager.chronometers {
-					chronUI := chronometersUI[i]
-					timeText := chronUI.GetItem(1).(*tview.TextView)
-					statusText := statusTexts[i]
+	// detailsAction shows a summary of one timer's activity
+	detailsAction := func(id int) {
+		c, _ := manager.copyOf(id)
+		text := fmt.Sprintf("%s\n\nElapsed: %s\nStarts: %d\nStarted at: %s\nStopped at: %s\nShare of total: %.0f%%\n", c.displayLabel, c.FormattedElapsedWithDays(),
+			manager.StartCount(id), formatClock(c.StartedAt()), formatClock(c.StoppedAt()), manager.Shares()[id]*100)
+		if len(c.segments) > 0 {
+			text += fmt.Sprintf("Running %.0f%% of wall time since %s", manager.Utilization(id)*100, c.segments[0].Start.Format("15:04:05"))
+		} else {
+			text += "Not started yet"
+		}
+		if len(c.laps) >= 2 {
+			fastest, mean, slowest, stddev := c.LapStats()
+			text += fmt.Sprintf("\n\n%d laps\nMin: %s\nAvg: %s\nMax: %s\nStd dev: %s", len(c.laps),
+				formatDuration(fastest), formatDuration(mean), formatDuration(slowest), formatDuration(stddev))
+		}
+		modal := tview.NewModal().
+			SetText(text).
+			AddButtons([]string{"OK"}).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				app.SetRoot(grid, true)
+			})
+		app.SetRoot(modal, false)
+	}
 
-					elapsed := c.GetElapsedTime()
-					timeText.SetText(fmt.Sprintf("[yellow]%s", formatDuration(elapsed)))
+	// withTimer adapts an action on the focused timer; without one the key
+	// is passed on
+	withTimer := func(action func(id int)) func() bool {
+		return func() bool {
+			id := focusedTimer()
+			if id < 0 {
+				return false
+			}
+			manager.Touch(id)
+			action(id)
+			return true
+		}
+	}
+	// withCurrent adapts an action on the focused timer or, with focus
+	// elsewhere, the one focused last
+	withCurrent := func(action func(id int)) func() bool {
+		return func() bool {
+			if len(views) == 0 {
+				return false
+			}
+			id := manager.Current()
+			manager.Touch(id)
+			action(id)
+			return true
+		}
+	}
+	// focusTimer moves focus to the nth timer, if there is one
+	focusTimer := func(n int) func() bool {
+		return func() bool {
+			if n > len(shown) {
+				return false
+			}
+			manager.SetCurrent(shown[n-1])
+			return focusView(shown[n-1])
+		}
+	}
+	// always adapts an action that handles its key in any case
+	always := func(action func()) func() bool {
+		return func() bool {
+			action()
+			return true
+		}
+	}
 
-					if c.isRunning {
-						statusText.SetText("Status: Running")
-						chronUI.SetTitle(fmt.Sprintf(" Timer %d [green]● ", i+1))
-					} else {
-						statusText.SetText("Status: Stopped")
-						chronUI.SetTitle(fmt.Sprintf(" Timer %d ", i+1))
-					}
+	// actions holds what each keymap action does. A handler returns false
+	// to pass the key on.
+	actions := map[string]func() bool{
+		"exit":         always(quitAction),
+		"interrupt":    always(shutdown),
+		"quit":         always(quitAction),
+		"save":         always(saveAction),
+		"load":         always(loadAction),
+		"export":       always(exportAction),
+		"export-ics":   always(icsAction),
+		"export-chart": always(chartAction),
+		"export-md":    always(markdownAction),
+		"laps":         always(lapsAction),
+		"compare":      always(compareAction),
+		"filter":       always(filterAction),
+		"reset-labels": always(resetLabelsAction),
+		"bulk":         always(bulkAction),
+		"snapshot": always(func() {
+			if err := manager.Snapshot(*snapshotFile); err != nil {
+				logEvent("Error writing snapshot: %v", err)
+			} else {
+				logEvent("Snapshot appended to %s", *snapshotFile)
+			}
+		}),
+		"precision": always(func() {
+			for i, name := range precisionNames {
+				if name == precision {
+					precision = precisionNames[(i+1)%len(precisionNames)]
+					break
 				}
-			})
-		}
-	}()
+			}
+			logEvent("Precision: %s", precision)
+			persisted.Precision = precision
+			if err := savePreferences(persisted); err != nil {
+				logEvent("Error saving config: %v", err)
+			}
+		}),
+		"hide-panel":  always(toggleButtonPanel),
+		"select":      withTimer(func(id int) { views[id].ToggleSelected() }),
+		"leaderboard": always(leaderboardAction),
+		"schedule":    withTimer(scheduleAction),
+		"shares":      always(sharesAction),
+		"tap":         withTimer(manager.Tap),
+		"details":     withTimer(detailsAction),
+		"rename": func() bool {
+			id := focusedTimer()
+			if id < 0 || !prefs.TitleLabels {
+				return false
+			}
+			renaming = id
+			applyLayout()
+			app.SetFocus(views[id].LabelField())
+			return true
+		},
+		"dense": always(func() {
+			dense = !dense
+			applyLayout()
+		}),
+		"columns": always(func() {
+			prefs.Columns = (prefs.Columns + 1) % (maxCycledColumns + 1)
+			placeTimers()
+			if prefs.Columns == 0 {
+				logEvent("Columns: automatic")
+			} else {
+				logEvent("Columns: %d", prefs.Columns)
+			}
+			persisted.Columns = prefs.Columns
+			if err := savePreferences(persisted); err != nil {
+				logEvent("Error saving config: %v", err)
+			}
+		}),
+		"add-timer": always(func() {
+			c, err := manager.AddChronometer()
+			if err != nil {
+				logEvent("Error adding timer: %v", err)
+				return
+			}
+			layoutTimers()
+			focusView(len(views) - 1)
+			logEvent("Added %s", c.displayLabel)
+		}),
+		"remove-timer": withTimer(removeAction),
+		"autosave":     always(toggleAutoSave),
+		"start":        withCurrent(manager.StartChronometer),
+		"stop":         withCurrent(stopTimer),
+		"reset-timer":  withCurrent(manager.ResetChronometer),
+		"restart":      withCurrent(manager.RestartChronometer),
+		"undo-reset": withCurrent(func(id int) {
+			c, _ := manager.copyOf(id)
+			if manager.UndoReset(id) {
+				logEvent("Undid the last reset of %s", c.displayLabel)
+			} else {
+				logEvent("No reset of %s to undo", c.displayLabel)
+			}
+		}),
+		"focus-1":      focusTimer(1),
+		"focus-2":      focusTimer(2),
+		"focus-3":      focusTimer(3),
+		"focus-4":      focusTimer(4),
+		"focus-5":      focusTimer(5),
+		"focus-6":      focusTimer(6),
+		"focus-7":      focusTimer(7),
+		"focus-8":      focusTimer(8),
+		"focus-9":      focusTimer(9),
+		"search":       always(func() { app.SetFocus(searchField) }),
+		"category":     withTimer(categoryAction),
+		"edit-elapsed": withTimer(func(id int) { views[id].EditElapsed() }),
+		"merge":        always(mergeAction),
+		"pomodoro": withCurrent(func(id int) {
+			c, _ := manager.copyOf(id)
+			if c.pomodoroPhase == "" {
+				manager.StartPomodoro(id)
+				logEvent("Pomodoro started on %s", c.displayLabel)
+				return
+			}
+			manager.SkipPomodoroPhase(id)
+			logEvent("Skipped the %s phase of %s", c.pomodoroPhase, c.displayLabel)
+		}),
+		"sort": always(func() {
+			sortMode = (sortMode + 1) % len(SortKeys)
+			order, _ = manager.SortBy(SortKeys[sortMode])
+			placeTimers()
+			focusView(manager.Current())
+			logEvent("Timers sorted by %s", SortKeys[sortMode])
+		}),
+		"exclusive": always(func() {
+			manager.SetExclusiveMode(!manager.ExclusiveMode())
+			updateTitleBar()
+			if manager.ExclusiveMode() {
+				logEvent("Exclusive mode: starting a timer stops the others")
+			} else {
+				logEvent("Concurrent mode: timers can run at the same time")
+			}
+		}),
+		"adjust-up":   withTimer(func(id int) { manager.AdjustTarget(id, prefs.AdjustStep) }),
+		"adjust-down": withTimer(func(id int) { manager.AdjustTarget(id, -prefs.AdjustStep) }),
+	}
+	keymap, _ := prefs.Keys.bindings() // checked by prefs.validate
+
+	// editingActions are the only actions whose keys work while a text
+	// field has focus. Every other key, Esc and the field's own Ctrl editing
+	// keys included, goes to the field.
+	editingActions := map[string]bool{"quit": true, "interrupt": true, "save": true}
 
-	// Handle keyboard shortcuts
+	// Handle keyboard shortcuts through the keymap. Dialogs and prompts get
+	// every key themselves, and a text field being edited gets every key
+	// but those of editingActions.
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEsc {
-			app.Stop()
+		// Whatever the key changes is shown once it has been handled
+		requestRedraw()
+		if !grid.HasFocus() || searchField.HasFocus() {
+			return event
+		}
+		if id := focusedTimer(); id >= 0 {
+			manager.SetCurrent(id)
+		}
+		key := keyName(event)
+		action, ok := keymap[key]
+		if !ok {
+			return event
+		}
+		if _, editing := app.GetFocus().(*tview.InputField); editing && !editingActions[action] {
+			return event
+		}
+		if actions[action]() {
 			return nil
 		}
 		return event
 	})
 
-	// Enable mouse support
+	// With -autosave, restore the newest readable save and keep saving.
+	// Each save rotates the previous ones into numbered backups.
+	if *autosave != "" {
+		restored, err := manager.LoadNewestBackup(*autosave, *autosaveKeep, *resumeRunning)
+		switch {
+		case os.IsNotExist(err):
+			// Nothing saved yet
+		case err != nil:
+			logEvent("Could not restore %s or its backups: %v", *autosave, err)
+		case restored != *autosave:
+			logEvent("%s could not be read; restored the backup %s", *autosave, restored)
+		default:
+			logEvent("Restored %s", restored)
+		}
+		for _, view := range views {
+			view.Refresh()
+		}
+		toggleAutoSave()
+	}
+
+	// Serve the HTTP control API
+	if *apiAddr != "" {
+		var limiter *rateLimiter
+		if *apiRate > 0 {
+			limiter = newRateLimiter(*apiRate, time.Minute)
+		}
+		server := &http.Server{Addr: *apiAddr, Handler: newAPIHandler(manager, *apiToken, limiter)}
+		go func() {
+			if err := server.ListenAndServe(); err != nil {
+				app.QueueUpdateDraw(func() {
+					logEvent("Control API stopped: %v", err)
+				})
+			}
+		}()
+	}
+
+	// With -pause-on-blur, pause running timers while the terminal is in the
+	// background. Terminals without focus reporting never send the events,
+	// so nothing is paused there.
+	if *pauseOnBlur {
+		screen, err := tcell.NewScreen()
+		if err != nil {
+			panic(err)
+		}
+		var paused []int
+		var blurredAt time.Time
+		app.SetScreen(&focusScreen{Screen: screen, onFocus: func(focused bool) {
+			app.QueueUpdateDraw(func() {
+				if !focused {
+					if !blurredAt.IsZero() {
+						return
+					}
+					blurredAt = time.Now()
+					paused = manager.PauseAll()
+					logEvent("Terminal lost focus: paused %d timers", len(paused))
+					return
+				}
+				if blurredAt.IsZero() {
+					return
+				}
+				manager.Resume(paused)
+				logEvent("Terminal focused after %s away: resumed %d timers", formatDuration(time.Since(blurredAt)), len(paused))
+				paused, blurredAt = nil, time.Time{}
+			})
+		}})
+		screen.EnableFocus()
+	}
+
+	// Enable mouse support. Clicks may change what the timers show, as
+	// keys do.
 	app.EnableMouse(true)
+	app.SetMouseCapture(func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+		if action != tview.MouseMove {
+			requestRedraw()
+		}
+		return event, action
+	})
+
+	// Run the application, back on the timer current in the restored save
+	app.SetRoot(grid, true)
+	if *autosave != "" {
+		focusView(manager.Current())
+	}
+
+	// Offer to restore the timers saved when the last session was
+	// interrupted. The file is removed once restored or discarded.
+	if *recoveryFile != "" {
+		recoveryPath := manager.resolvePath(*recoveryFile)
+		data, err := readSaveFile(recoveryPath)
+		switch {
+		case os.IsNotExist(err):
+			// Nothing to recover
+		case err != nil:
+			logEvent("Could not read %s: %v", recoveryPath, err)
+		default:
+			modal := tview.NewModal().
+				SetText(fmt.Sprintf("%d timers were saved to %s when the last session ended, at %s.\n\nRestore them?",
+					len(data.Chronometers), recoveryPath, data.SaveTime.Local().Format("2006-01-02 15:04:05"))).
+				AddButtons([]string{"Restore", "Discard", "Not now"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					switch buttonLabel {
+					case "Restore":
+						if err := manager.LoadFromFile(recoveryPath, *resumeRunning); err != nil {
+							logEvent("Error restoring %s: %v", recoveryPath, err)
+							break
+						}
+						for _, view := range views {
+							view.Refresh()
+						}
+						logEvent("Restored %s", recoveryPath)
+						if err := os.Remove(recoveryPath); err != nil {
+							logEvent("Error removing %s: %v", recoveryPath, err)
+						}
+					case "Discard":
+						if err := os.Remove(recoveryPath); err != nil {
+							logEvent("Error removing %s: %v", recoveryPath, err)
+						}
+					}
+					app.SetRoot(grid, true)
+					focusView(manager.Current())
+				})
+			app.SetRoot(modal, false)
+		}
+	}
 
-	// Run the application
-	if err := app.SetRoot(grid, true).Run(); err != nil {
+	if err := app.Run(); err != nil {
 		panic(err)
 	}
+	select {
+	case report := <-shutdownReport:
+		fmt.Fprintln(os.Stderr, report)
+	default:
+	}
+
+	// Remember the settings changed while running
+	now := prefs
+	now.Precision, now.Dense, now.ExportFilter = precision, dense, exportFilter
+	persisted = keepRuntimeChanges(persisted, prefs, now)
+	if err := savePreferences(persisted); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+	}
+
+	printQuitSummary()
 }
@@ -0,0 +1,1387 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestKeymapRejectsDuplicateBindings(t *testing.T) {
+	keys := defaultKeymap()
+	keys["save"] = "p"
+	keys["load"] = "P"
+
+	_, err := keys.bindings()
+	if err == nil {
+		t.Fatal("bindings() accepted two actions on one key")
+	}
+	for _, want := range []string{"p is bound to save, snapshot", "P is bound to load, pomodoro"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestKeymapDefaultsHaveNoConflicts(t *testing.T) {
+	if _, err := defaultKeymap().bindings(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		key, want string
+		ok        bool
+	}{
+		{"p", "p", true},
+		{"space", "Space", true},
+		{"ESCAPE", "Esc", true},
+		{"ctrl+s", "Ctrl-S", true},
+		{"Ctrl-H", "", false},
+		{"ctrl-i", "", false},
+		{"Ctrl+M", "", false},
+		{"F13", "", false},
+	}
+	for _, tt := range tests {
+		got, err := normalizeKey(tt.key)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("normalizeKey(%q) = %q, %v; want %q, ok %v", tt.key, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAPIMutationsFollowTimerIDsAfterRemove(t *testing.T) {
+	manager := NewChronoManager(3)
+	if err := manager.RemoveChronometer(1); err != nil {
+		t.Fatal(err)
+	}
+	handler := newAPIHandler(manager, "", nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/start?id=2", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("POST /start?id=2: status %d", rec.Code)
+	}
+	if c, _ := manager.copyOf(0); c.id != 2 || !c.isRunning {
+		t.Errorf("timer %d running %v, want timer 2 running", c.id, c.isRunning)
+	}
+
+	for _, id := range []string{"1", "4", "x"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/stop?id="+id, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("POST /stop?id=%s: status %d, want 404", id, rec.Code)
+		}
+	}
+}
+
+func TestRemoveChronometerByID(t *testing.T) {
+	manager := NewChronoManager(3)
+	if err := manager.RemoveChronometer(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.RemoveChronometer(1); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := manager.copyOf(0); manager.Count() != 1 || c.id != 3 {
+		t.Errorf("%d timers left, first is timer %d; want timer 3 alone", manager.Count(), c.id)
+	}
+
+	err := manager.RemoveChronometer(2)
+	if !errors.Is(err, ErrTimerNotFound) || !strings.HasSuffix(err.Error(), ": 2") {
+		t.Errorf("removing timer 2 twice: %v", err)
+	}
+}
+
+func TestAddChronometerNeverReusesIDs(t *testing.T) {
+	manager := NewChronoManager(2)
+	if err := manager.RemoveChronometer(2); err != nil {
+		t.Fatal(err)
+	}
+	c, err := manager.AddChronometer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID() != 3 {
+		t.Errorf("added timer %d after removing timer 2, want 3", c.ID())
+	}
+
+	// The timer returned is a copy
+	c.Start()
+	if manager.RunningCount() != 0 {
+		t.Error("starting the returned timer started the manager's")
+	}
+}
+
+func TestSortByKeepsStorageOrder(t *testing.T) {
+	manager := NewChronoManager(3)
+	for i, label := range []string{"beta", "Alpha", "gamma"} {
+		manager.RenameChronometer(i, label)
+	}
+	manager.SetElapsed(0, time.Minute)
+	manager.SetElapsed(2, time.Hour)
+
+	tests := []struct {
+		key  string
+		want []int
+	}{
+		{"id", []int{0, 1, 2}},
+		{"label", []int{1, 0, 2}},
+		{"elapsed", []int{2, 0, 1}},
+	}
+	for _, tt := range tests {
+		order, err := manager.SortBy(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(order, tt.want) {
+			t.Errorf("SortBy(%q) = %v, want %v", tt.key, order, tt.want)
+		}
+	}
+	for i, c := range manager.Chronometers() {
+		if c.ID() != i+1 {
+			t.Errorf("index %d holds timer %d after sorting", i, c.ID())
+		}
+	}
+	if _, err := manager.SortBy("size"); err == nil {
+		t.Error("SortBy accepted an unknown key")
+	}
+}
+
+func TestMergeTimers(t *testing.T) {
+	manager := NewChronoManager(3)
+	manager.SetElapsed(0, 10*time.Second)
+	manager.SetElapsed(1, 5*time.Second)
+	manager.LapChronometer(0)
+	manager.LapChronometer(1)
+
+	if err := manager.MergeTimers(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	src, _ := manager.copyOf(0)
+	dst, _ := manager.copyOf(1)
+	if src.elapsed() != 0 || len(src.laps) != 0 || src.undoable {
+		t.Errorf("source left with %v, laps %v, undoable %v", src.elapsed(), src.laps, src.undoable)
+	}
+	if dst.elapsed() != 15*time.Second || !reflect.DeepEqual(dst.laps, []time.Duration{5 * time.Second, 10 * time.Second}) {
+		t.Errorf("destination has %v and laps %v", dst.elapsed(), dst.laps)
+	}
+
+	if err := manager.MergeTimers(1, 1); err == nil {
+		t.Error("merged a timer into itself")
+	}
+	if err := manager.MergeTimers(0, 3); !errors.Is(err, ErrTimerNotFound) {
+		t.Errorf("merge into a missing timer: %v", err)
+	}
+
+	// The destination's maximum would cut the merged time short
+	manager.BulkSet([]int{2}, func(c *Chronometer) { c.SetMaxDuration(20 * time.Second) })
+	manager.SetElapsed(2, 10*time.Second)
+	if err := manager.MergeTimers(1, 2); err == nil {
+		t.Error("merge past the destination's maximum was accepted")
+	}
+	if c, _ := manager.copyOf(1); c.elapsed() != 15*time.Second {
+		t.Errorf("refused merge changed the source to %v", c.elapsed())
+	}
+}
+
+func TestPreferencePrecedence(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	newFlags := func(args ...string) *flag.FlagSet {
+		fs := flag.NewFlagSet("metrochrono", flag.ContinueOnError)
+		fs.String("display-precision", "milliseconds", "")
+		fs.Bool("dense", false, "")
+		fs.Int("columns", 0, "")
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	}
+
+	// Defaults without a config file or flags
+	persisted, err := loadPreferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefs := applyFlagOverrides(persisted, newFlags())
+	if prefs.Precision != "milliseconds" || prefs.Dense || prefs.Columns != 0 {
+		t.Errorf("defaults: got %q, dense %v, %d columns", prefs.Precision, prefs.Dense, prefs.Columns)
+	}
+
+	// The config file beats the defaults, including flags left at theirs
+	stored := defaultPreferences()
+	stored.Precision, stored.Columns = "seconds", 4
+	if err := savePreferences(stored); err != nil {
+		t.Fatal(err)
+	}
+	persisted, err = loadPreferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefs = applyFlagOverrides(persisted, newFlags())
+	if prefs.Precision != "seconds" || prefs.Columns != 4 {
+		t.Errorf("config: got %q, %d columns", prefs.Precision, prefs.Columns)
+	}
+
+	// Flags given explicitly beat the config file
+	prefs = applyFlagOverrides(persisted, newFlags("-display-precision", "centiseconds", "-dense"))
+	if prefs.Precision != "centiseconds" || !prefs.Dense || prefs.Columns != 4 {
+		t.Errorf("flags: got %q, dense %v, %d columns", prefs.Precision, prefs.Dense, prefs.Columns)
+	}
+
+	// Only settings changed while running are written back, not the flags
+	now := prefs
+	now.ExportFilter = "running"
+	kept := keepRuntimeChanges(persisted, prefs, now)
+	if kept.Precision != "seconds" || kept.Dense || kept.ExportFilter != "running" {
+		t.Errorf("written back: got %q, dense %v, filter %q", kept.Precision, kept.Dense, kept.ExportFilter)
+	}
+}
+
+// Run with -race to check the locking
+func TestConcurrentStartChronometer(t *testing.T) {
+	manager := NewChronoManager(4)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				manager.StartChronometer((g + i) % 4)
+				if i%10 == 0 {
+					manager.StopChronometer(g % 4)
+				}
+				manager.FindRunning()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if n := manager.RunningCount(); n > 1 {
+		t.Errorf("%d timers running in exclusive mode", n)
+	}
+}
+
+func TestLapStats(t *testing.T) {
+	seconds := func(values ...float64) []time.Duration {
+		laps := make([]time.Duration, len(values))
+		for i, v := range values {
+			laps[i] = time.Duration(v * float64(time.Second))
+		}
+		return laps
+	}
+	tests := []struct {
+		name                       string
+		laps                       []time.Duration
+		shortest, avg, longest, sd time.Duration
+	}{
+		{"no laps", nil, 0, 0, 0, 0},
+		{"one lap", seconds(3), 0, 0, 0, 0},
+		{"equal laps", seconds(2, 2, 2), 2 * time.Second, 2 * time.Second, 2 * time.Second, 0},
+		// The sample standard deviation is √(32/7) s
+		{"known set", seconds(2, 4, 4, 4, 5, 5, 7, 9), 2 * time.Second, 5 * time.Second, 9 * time.Second,
+			time.Duration(math.Round(math.Sqrt(32.0/7) * float64(time.Second)))},
+		{"two laps", seconds(1, 3), time.Second, 2 * time.Second, 3 * time.Second,
+			time.Duration(math.Round(math.Sqrt2 * float64(time.Second)))},
+	}
+	for _, tt := range tests {
+		c := NewChronometer(1)
+		c.laps = tt.laps
+		shortest, avg, longest, sd := c.LapStats()
+		if shortest != tt.shortest || avg != tt.avg || longest != tt.longest || sd != tt.sd {
+			t.Errorf("%s: LapStats() = %v, %v, %v, %v; want %v, %v, %v, %v",
+				tt.name, shortest, avg, longest, sd, tt.shortest, tt.avg, tt.longest, tt.sd)
+		}
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	manager := NewChronoManager(2)
+	manager.RenameChronometer(0, "a|b")
+	manager.SetElapsed(0, 90*time.Second)
+	manager.SetElapsed(1, 1500*time.Millisecond)
+
+	filename := filepath.Join(t.TempDir(), "report.md")
+	if err := manager.ExportMarkdown(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	want := []string{
+		"| Timer | Label | Elapsed | Status |",
+		"|------:|-------|--------:|--------|",
+		`| 1 | a\|b | 00:01:30.000 | Stopped |`,
+		"| 2 | Timer 2 | 00:00:01.500 | Stopped |",
+		"| | **Total** | **00:01:31.500** | |",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+	for _, line := range lines {
+		if n := strings.Count(line, "|") - strings.Count(line, `\|`); n != 5 {
+			t.Errorf("%q has %d column pipes, want 5", line, n)
+		}
+	}
+}
+
+func TestFormatDurationWithDays(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{24*time.Hour - time.Millisecond, "23:59:59.999"},
+		{24 * time.Hour, "1d 00:00:00.000"},
+		{99*time.Hour + 59*time.Minute, "4d 03:59:00.000"},
+		{100 * time.Hour, "4d 04:00:00.000"},
+	}
+	for _, tt := range tests {
+		if got := formatDurationWithDays(tt.d); got != tt.want {
+			t.Errorf("formatDurationWithDays(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestParseDurationRoundTrip(t *testing.T) {
+	for _, d := range []time.Duration{
+		0,
+		1500 * time.Millisecond,
+		59*time.Minute + 59*time.Second + 999*time.Millisecond,
+		23*time.Hour + 7*time.Millisecond,
+		24 * time.Hour,
+		100*time.Hour + 30*time.Second + 250*time.Millisecond,
+	} {
+		for _, s := range []string{formatDuration(d), formatDurationWithDays(d)} {
+			got, err := parseDuration(s)
+			if err != nil || got != d {
+				t.Errorf("parseDuration(%q) = %v, %v; want %v", s, got, err, d)
+			}
+		}
+	}
+
+	for _, s := range []string{"", "1:00", "00:60:00", "00:00:60", "1d 00:00", "1d 1:00:00:00", "1x 00:00:00", "00:00:00.", "aa:00:00"} {
+		if _, err := parseDuration(s); !errors.Is(err, ErrInvalidTimeFormat) {
+			t.Errorf("parseDuration(%q) error %v, want ErrInvalidTimeFormat", s, err)
+		}
+	}
+}
+
+func TestParseDurationOverflow(t *testing.T) {
+	for _, s := range []string{"99999999999999999999:00:00", "2562048:00:00", "2562047:47:16.9", "106751d 23:47:17"} {
+		_, err := parseDuration(s)
+		var pe *ParseError
+		if !errors.Is(err, ErrInvalidTimeFormat) || !errors.As(err, &pe) {
+			t.Errorf("parseDuration(%q) error %v, want a ParseError wrapping ErrInvalidTimeFormat", s, err)
+		}
+	}
+	if d, err := parseDuration("2562047:47:16.854775807"); err != nil || d != math.MaxInt64 {
+		t.Errorf("largest duration parsed as %v, %v", d, err)
+	}
+}
+
+// writeSave writes data as a JSON save file in a temporary directory and
+// returns its name
+func writeSave(t *testing.T, data SaveData) string {
+	t.Helper()
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := os.WriteFile(filename, jsonData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadResumeRunning(t *testing.T) {
+	filename := writeSave(t, SaveData{
+		SaveTime: time.Now().Add(-time.Hour),
+		Chronometers: []ChronoData{
+			{ID: 1, DisplayLabel: "running", ElapsedTime: 10 * time.Minute, IsRunning: true},
+			{ID: 2, DisplayLabel: "stopped", ElapsedTime: 10 * time.Minute},
+		},
+	})
+
+	tests := []struct {
+		resume         bool
+		running, other time.Duration
+	}{
+		{false, 10 * time.Minute, 10 * time.Minute},
+		{true, 70 * time.Minute, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		manager := NewChronoManager(2)
+		if err := manager.LoadFromFile(filename, tt.resume); err != nil {
+			t.Fatal(err)
+		}
+		running, _ := manager.copyOf(0)
+		stopped, _ := manager.copyOf(1)
+		if !running.isRunning || stopped.isRunning {
+			t.Errorf("resume %v: running %v and %v", tt.resume, running.isRunning, stopped.isRunning)
+		}
+		if got := running.GetElapsedTime(); got < tt.running || got > tt.running+time.Second {
+			t.Errorf("resume %v: running timer at %v, want %v", tt.resume, got, tt.running)
+		}
+		if got := stopped.GetElapsedTime(); got != tt.other {
+			t.Errorf("resume %v: stopped timer at %v, want %v", tt.resume, got, tt.other)
+		}
+	}
+}
+
+func TestResetWhileRunning(t *testing.T) {
+	manager := NewChronoManager(2)
+	manager.SetElapsed(0, time.Hour)
+	manager.SetElapsed(1, time.Hour)
+	manager.StartChronometer(0)
+
+	manager.ResetChronometer(0)
+	manager.ResetChronometer(1)
+
+	running, _ := manager.copyOf(0)
+	if !running.isRunning || running.GetElapsedTime() > time.Second {
+		t.Errorf("running timer reset to %v, running %v; want near zero and running", running.GetElapsedTime(), running.isRunning)
+	}
+	stopped, _ := manager.copyOf(1)
+	if stopped.isRunning || stopped.GetElapsedTime() != 0 {
+		t.Errorf("stopped timer reset to %v, running %v; want zero and stopped", stopped.GetElapsedTime(), stopped.isRunning)
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	manager := NewChronoManager(2)
+	manager.RenameChronometer(0, "review")
+	manager.SetElapsed(0, 90*time.Second+500*time.Millisecond)
+	if err := manager.SetCategory(0, "work"); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "export.json")
+	if err := manager.ExportJSON(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("export is not a JSON array of objects: %v", err)
+	}
+	want := []map[string]any{
+		{"id": 1.0, "label": "review", "elapsed_seconds": 90.5, "elapsed_human": "00:01:30.500", "running": false, "category": "work"},
+		{"id": 2.0, "label": "Timer 2", "elapsed_seconds": 0.0, "elapsed_human": "00:00:00.000", "running": false, "category": ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
+
+func TestUndoReset(t *testing.T) {
+	manager := NewChronoManager(2)
+	manager.SetElapsed(0, time.Hour)
+	manager.SetElapsed(1, time.Hour)
+	manager.StartChronometer(0)
+	manager.ResetChronometer(0)
+	manager.ResetChronometer(1)
+
+	for id := range 2 {
+		if !manager.UndoReset(id) {
+			t.Fatalf("timer %d: nothing to undo", id+1)
+		}
+		if manager.UndoReset(id) {
+			t.Errorf("timer %d: a reset was undone twice", id+1)
+		}
+	}
+
+	// The time counted since the reset comes on top of the hour restored
+	running, _ := manager.copyOf(0)
+	if got := running.GetElapsedTime(); !running.isRunning || got < time.Hour || got > time.Hour+time.Second {
+		t.Errorf("reset while running undone to %v, running %v", got, running.isRunning)
+	}
+	stopped, _ := manager.copyOf(1)
+	if got := stopped.GetElapsedTime(); stopped.isRunning || got != time.Hour {
+		t.Errorf("reset while stopped undone to %v, running %v", got, stopped.isRunning)
+	}
+}
+
+func TestResumeKeepsTheRunsStart(t *testing.T) {
+	c := NewChronometer(1)
+	c.Start()
+	startedAt := c.startedAt
+	c.Pause()
+	c.changes = nil
+	time.Sleep(time.Millisecond)
+	c.Resume()
+
+	if !c.isRunning || c.paused {
+		t.Fatalf("running %v, paused %v after Resume", c.isRunning, c.paused)
+	}
+	if c.startCount != 1 || !c.startedAt.Equal(startedAt) {
+		t.Errorf("%d starts from %v, want 1 from %v", c.startCount, c.startedAt, startedAt)
+	}
+	if len(c.segments) != 2 || len(c.changes) != 1 || c.changes[0].event != EventStart {
+		t.Errorf("segments %v, changes %v", c.segments, c.changes)
+	}
+
+	// Resuming a timer that isn't paused does nothing
+	c.Resume()
+	if len(c.segments) != 2 {
+		t.Errorf("a second Resume opened another run: %v", c.segments)
+	}
+}
+
+func TestClickedResetCanBeUndone(t *testing.T) {
+	manager := NewChronoManager(1)
+	manager.SetElapsed(0, time.Hour)
+	resets := 0
+	manager.OnStateChange(func(id int, event string, at time.Time) {
+		if event == EventReset {
+			resets++
+		}
+	})
+	view := NewTimerView(tview.NewApplication(), manager, 0)
+	reset := view.buttons.GetItem(3)
+	reset.SetRect(0, 0, 10, 1)
+
+	click := tcell.NewEventMouse(1, 0, tcell.Button1, tcell.ModNone)
+	reset.MouseHandler()(tview.MouseLeftClick, click, func(tview.Primitive) {})
+	if resets != 1 {
+		t.Errorf("one click sent %d reset events, want 1", resets)
+	}
+	if !manager.UndoReset(0) {
+		t.Fatal("nothing to undo after a clicked Reset")
+	}
+	if c, _ := manager.copyOf(0); c.GetElapsedTime() != time.Hour {
+		t.Errorf("undo restored %v, want %v", c.GetElapsedTime(), time.Hour)
+	}
+}
+
+func TestLoadNewestBackupSkipsCorruptSave(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "autosave.json")
+	manager := NewChronoManager(1)
+	manager.SetElapsed(0, time.Minute)
+	if err := manager.SaveRotated(filename, 2); err != nil {
+		t.Fatal(err)
+	}
+	manager.SetElapsed(0, 2*time.Minute)
+	if err := manager.SaveRotated(filename, 2); err != nil {
+		t.Fatal(err)
+	}
+	// A crash mid-write leaves the newest save truncated
+	if err := os.WriteFile(filename, []byte(`{"chronometers": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewChronoManager(1)
+	name, err := restored.LoadNewestBackup(filename, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := backupName(filename, 1); name != want {
+		t.Errorf("loaded %s, want %s", name, want)
+	}
+	if c, _ := restored.copyOf(0); c.elapsed() != time.Minute {
+		t.Errorf("restored %v, want the backup's 1m0s", c.elapsed())
+	}
+
+	if _, err := NewChronoManager(1).LoadNewestBackup(filepath.Join(t.TempDir(), "none.json"), 2, false); !os.IsNotExist(err) {
+		t.Errorf("with no saves: %v, want a not-exist error", err)
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	manager := NewChronoManager(3)
+	if err := manager.RemoveChronometer(1); err != nil {
+		t.Fatal(err)
+	}
+	manager.RenameChronometer(0, "second")
+	manager.StartChronometer(0)
+
+	if n := manager.Count(); n != 2 {
+		t.Errorf("Count() = %d, want 2", n)
+	}
+	c, ok := manager.Get(2)
+	if !ok || c.ID() != 2 || c.DisplayLabel() != "second" || !c.IsRunning() {
+		t.Errorf("Get(2) = timer %d %q, running %v, %v", c.ID(), c.DisplayLabel(), c.IsRunning(), ok)
+	}
+	for _, id := range []int{0, 1, 4} {
+		if _, ok := manager.Get(id); ok {
+			t.Errorf("Get(%d) found a timer", id)
+		}
+	}
+
+	// The copies don't follow later changes
+	chronometers := manager.Chronometers()
+	manager.StopChronometer(0)
+	if len(chronometers) != 2 || chronometers[0].ID() != 2 || !chronometers[0].IsRunning() || chronometers[1].ID() != 3 {
+		t.Errorf("Chronometers() changed after a stop")
+	}
+	if c, _ := manager.Get(2); c.IsRunning() {
+		t.Error("Get(2) still running after a stop")
+	}
+
+	// Changing a copy leaves the manager's timer alone
+	manager.StartChronometer(1)
+	manager.LapChronometer(1)
+	copied, _ := manager.Get(3)
+	copied.Lap()
+	copied.Stop()
+	live, _ := manager.copyOf(1)
+	if !live.isRunning || len(live.laps) != 1 || !live.segments[len(live.segments)-1].End.IsZero() {
+		t.Errorf("stopping a copy changed the timer: running %v, laps %v, segments %v", live.isRunning, live.laps, live.segments)
+	}
+}
+
+// readCSV reads back a CSV export, mapping each column name of the header
+// to its index
+func readCSV(t *testing.T, filename string) ([][]string, map[string]int) {
+	t.Helper()
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	return records, columns
+}
+
+func TestSaveToCSVColumns(t *testing.T) {
+	manager := NewChronoManager(2)
+	manager.SetElapsed(0, 1500*time.Millisecond)
+	manager.StartChronometer(1)
+
+	filename := filepath.Join(t.TempDir(), "export.csv")
+	if err := manager.SaveToCSV(filename); err != nil {
+		t.Fatal(err)
+	}
+	records, columns := readCSV(t, filename)
+	for _, name := range []string{"Status", "Exported At", "Elapsed (ns)"} {
+		if _, ok := columns[name]; !ok {
+			t.Fatalf("no %s column in %v", name, records[0])
+		}
+	}
+	if len(records) != 4 {
+		t.Fatalf("%d records, want a header, two timers and a total", len(records))
+	}
+
+	for i, want := range []string{"Stopped", "Running"} {
+		record := records[i+1]
+		if got := record[columns["Status"]]; got != want {
+			t.Errorf("timer %d status %q, want %q", i+1, got, want)
+		}
+		if _, err := time.Parse(time.RFC3339, record[columns["Exported At"]]); err != nil {
+			t.Errorf("timer %d: %v", i+1, err)
+		}
+		if record[columns["Exported At"]] != records[1][columns["Exported At"]] {
+			t.Error("the rows were exported at different times")
+		}
+	}
+	if got := records[1][columns["Elapsed (ns)"]]; got != "1500000000" {
+		t.Errorf("raw elapsed %q, want 1500000000", got)
+	}
+	if ns, err := strconv.ParseInt(records[2][columns["Elapsed (ns)"]], 10, 64); err != nil || ns < 0 || ns > int64(time.Second) {
+		t.Errorf("running timer raw elapsed %d, %v", ns, err)
+	}
+}
+
+// Run with -race to check that the UI's reads and the autosave don't race
+// changes from other goroutines
+func TestConcurrentReadsAndChanges(t *testing.T) {
+	manager := NewChronoManager(3)
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	changes := []func(int){
+		manager.ToggleChronometer,
+		manager.PauseChronometer,
+		manager.LapChronometer,
+		manager.ResetChronometer,
+		func(id int) { manager.BulkSet([]int{id}, func(c *Chronometer) { c.SetBudget(time.Hour) }) },
+	}
+	for g, change := range changes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				change((g + i) % 3)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			for id := 0; id < 3; id++ {
+				c, _ := manager.copyOf(id)
+				c.GetElapsedTime()
+			}
+			manager.WriteQuitSummary(io.Discard)
+			if err := manager.SaveToFile(filepath.Join(dir, "autosave.json")); err != nil {
+				t.Error(err)
+			}
+			if err := manager.SaveToCSV(filepath.Join(dir, "export.csv")); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestParseHuman(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{"2.5h", 150 * time.Minute, true},
+		{"90m", 90 * time.Minute, true},
+		{"90 min", 90 * time.Minute, true},
+		{"1h 5m", 65 * time.Minute, true},
+		{"1h30m", 90 * time.Minute, true},
+		{" 45s ", 45 * time.Second, true},
+		{"01:30:00", 90 * time.Minute, true},
+		{"", 0, false},
+		{"garbage", 0, false},
+		{"5 apples", 0, false},
+		{"1h and 5m", 0, false},
+		{"10m!", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseHuman(tt.s)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseHuman(%q) = %v, %v; want %v, ok %v", tt.s, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	manager := NewChronoManager(3)
+	manager.RenameChronometer(0, "Code review")
+	manager.RenameChronometer(1, "standup")
+	manager.RenameChronometer(2, "REVIEW notes")
+	manager.SetElapsed(1, time.Minute)
+	manager.StartChronometer(2)
+
+	ids := func(found []ChronoData) []int {
+		var ids []int
+		for _, cd := range found {
+			ids = append(ids, cd.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		name  string
+		found []ChronoData
+		want  []int
+	}{
+		{"all", manager.Find(nil), []int{1, 2, 3}},
+		{"predicate", manager.Find(func(cd ChronoData) bool { return cd.ElapsedTime >= time.Minute }), []int{2}},
+		{"calling back", manager.Find(func(cd ChronoData) bool { return manager.Count() == cd.ID }), []int{3}},
+		{"by label", manager.FindByLabel("review"), []int{1, 3}},
+		{"no label", manager.FindByLabel("lunch"), nil},
+		{"running", manager.FindRunning(), []int{3}},
+	}
+	for _, tt := range tests {
+		if got := ids(tt.found); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: found %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got := manager.Filter("Review"); !reflect.DeepEqual(got, []int{0, 2}) {
+		t.Errorf("Filter(\"Review\") = %v, want [0 2]", got)
+	}
+
+	// The results are snapshots
+	found := manager.FindByLabel("standup")
+	found[0].DisplayLabel = "changed"
+	if c, _ := manager.copyOf(1); c.displayLabel != "standup" {
+		t.Errorf("changing a result renamed the timer to %q", c.displayLabel)
+	}
+}
+
+func TestDisplayGuard(t *testing.T) {
+	var g displayGuard
+	steps := []struct {
+		value, want        time.Duration
+		running, countdown bool
+		epoch              int
+	}{
+		{10 * time.Second, 10 * time.Second, true, false, 0},
+		// A small backward nudge of the clock is held off
+		{10*time.Second - 3*time.Millisecond, 10 * time.Second, true, false, 0},
+		{10*time.Second + time.Millisecond, 10*time.Second + time.Millisecond, true, false, 0},
+		// A reset starts a new epoch, so going back is shown
+		{time.Millisecond, time.Millisecond, true, false, 1},
+		// So is the value of a stopped timer
+		{0, 0, false, false, 1},
+		// Countdowns never go up
+		{time.Minute, time.Minute, true, true, 2},
+		{time.Minute + 2*time.Millisecond, time.Minute, true, true, 2},
+		{time.Minute - time.Second, time.Minute - time.Second, true, true, 2},
+	}
+	for i, s := range steps {
+		if got := g.apply(s.value, s.running, s.countdown, s.epoch); got != s.want {
+			t.Errorf("step %d: apply(%v) = %v, want %v", i, s.value, got, s.want)
+		}
+	}
+}
+
+func TestAPIRequiresToken(t *testing.T) {
+	handler := newAPIHandler(NewChronoManager(1), "secret", nil)
+	tests := []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/timers", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Authorization %q: status %d, want %d", tt.auth, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("Authorization %q: no WWW-Authenticate challenge", tt.auth)
+		}
+	}
+}
+
+func TestAPIRateLimit(t *testing.T) {
+	handler := newAPIHandler(NewChronoManager(1), "", newRateLimiter(2, time.Minute))
+	post := func(remote string) int {
+		req := httptest.NewRequest(http.MethodPost, "/start?id=1", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		if got := post("192.0.2.1:1234"); got != want {
+			t.Errorf("request %d: status %d, want %d", i+1, got, want)
+		}
+	}
+	// The limit is per client IP, whatever the port
+	if got := post("192.0.2.2:1234"); got != http.StatusNoContent {
+		t.Errorf("another client: status %d", got)
+	}
+	if got := post("192.0.2.1:5678"); got != http.StatusTooManyRequests {
+		t.Errorf("same client, new port: status %d", got)
+	}
+}
+
+func TestRateLimiterDropsExpiredWindows(t *testing.T) {
+	limiter := newRateLimiter(1, time.Minute)
+	start := time.Now()
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		limiter.Allow(ip, start)
+	}
+	if limiter.Allow("192.0.2.1", start.Add(time.Second)) {
+		t.Error("second request in the window allowed")
+	}
+
+	if !limiter.Allow("192.0.2.4", start.Add(time.Minute)) {
+		t.Error("request from a new client refused")
+	}
+	if n := len(limiter.clients); n != 1 {
+		t.Errorf("%d clients tracked after their windows ran out, want 1", n)
+	}
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	manager := NewChronoManager(3)
+	manager.RenameChronometer(0, "kept")
+	manager.SetElapsed(0, 90*time.Second)
+	manager.LapChronometer(0)
+	manager.SetElapsed(1, time.Hour)
+	manager.BulkSet([]int{0}, func(c *Chronometer) { c.selected = true })
+
+	filename := filepath.Join(t.TempDir(), "timers.bin")
+	if err := manager.SaveToGobFiltered(filename, func(c *Chronometer) bool { return c.selected }); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewChronoManager(3)
+	loaded.SetElapsed(1, time.Minute)
+	if err := loaded.LoadFromGob(filename); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := loaded.copyOf(0)
+	if c.displayLabel != "kept" || c.elapsed() != 90*time.Second || !reflect.DeepEqual(c.laps, []time.Duration{90 * time.Second}) {
+		t.Errorf("loaded %q at %v with laps %v", c.displayLabel, c.elapsed(), c.laps)
+	}
+	// Timers left out by the filter keep their own state
+	if c, _ := loaded.copyOf(1); c.elapsed() != time.Minute {
+		t.Errorf("filtered out timer changed to %v", c.elapsed())
+	}
+
+	// The JSON loader refuses the binary file without touching the timers
+	err := loaded.LoadFromFile(filename, false)
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Errorf("LoadFromFile of a binary save: %v, want a ParseError", err)
+	}
+	if c, _ := loaded.copyOf(0); c.elapsed() != 90*time.Second {
+		t.Errorf("failed load changed the timer to %v", c.elapsed())
+	}
+}
+
+func TestSaveToYAMLFiltered(t *testing.T) {
+	manager := NewChronoManager(2)
+	manager.SetElapsed(0, 90*time.Second)
+	manager.SetElapsed(1, time.Hour)
+	manager.BulkSet([]int{0}, func(c *Chronometer) { c.selected = true })
+
+	filename := filepath.Join(t.TempDir(), "timers.yaml")
+	if err := manager.SaveToYAMLFiltered(filename, func(c *Chronometer) bool { return c.selected }); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewChronoManager(2)
+	loaded.SetElapsed(1, time.Minute)
+	if err := loaded.LoadFromYAML(filename); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := loaded.copyOf(0); c.elapsed() != 90*time.Second {
+		t.Errorf("selected timer loaded at %v, want 1m30s", c.elapsed())
+	}
+	if c, _ := loaded.copyOf(1); c.elapsed() != time.Minute {
+		t.Errorf("filtered out timer changed to %v", c.elapsed())
+	}
+}
+
+func TestLoadWarnsAboutFutureSaveTime(t *testing.T) {
+	tests := []struct {
+		saved time.Duration
+		warn  bool
+	}{
+		{-time.Hour, false},
+		{saveTimeSkewTolerance / 2, false},
+		{time.Hour, true},
+	}
+	for _, tt := range tests {
+		filename := writeSave(t, SaveData{
+			SaveTime:     time.Now().Add(tt.saved),
+			Chronometers: []ChronoData{{ID: 1, DisplayLabel: "skewed", ElapsedTime: time.Minute, IsRunning: true}},
+		})
+		manager := NewChronoManager(1)
+		if err := manager.LoadFromFile(filename, true); err != nil {
+			t.Fatal(err)
+		}
+		warnings := manager.LoadWarnings()
+		if got := len(warnings) == 1 && strings.Contains(warnings[0], "in the future"); got != tt.warn {
+			t.Errorf("saved %v from now: warnings %q", tt.saved, warnings)
+		}
+		// A save from the future credits no time to running timers
+		if c, _ := manager.copyOf(0); tt.saved > 0 && c.GetElapsedTime() > time.Minute+time.Second {
+			t.Errorf("saved %v from now: resumed at %v", tt.saved, c.GetElapsedTime())
+		}
+	}
+}
+
+func TestDisplayAndExportPrecision(t *testing.T) {
+	d := time.Hour + 2*time.Minute + 3*time.Second + 456789*time.Microsecond
+	tests := []struct {
+		precision, display, export string
+	}{
+		{"seconds", "01:02:03", "01:02:03"},
+		{"centiseconds", "01:02:03.45", "01:02:03.46"},
+		{"milliseconds", "01:02:03.456", "01:02:03.457"},
+		{"microseconds", "01:02:03.456789", "01:02:03.456789"},
+	}
+	manager := NewChronoManager(1)
+	for _, tt := range tests {
+		// The display truncates, so a running timer never shows a second
+		// early; exports round
+		if got := formatDurationPrecision(d, precisionDigits[tt.precision]); got != tt.display {
+			t.Errorf("display at %s: %q, want %q", tt.precision, got, tt.display)
+		}
+		if err := manager.SetExportPrecision(tt.precision); err != nil {
+			t.Fatal(err)
+		}
+		if got := manager.formatExport(d); got != tt.export {
+			t.Errorf("export at %s: %q, want %q", tt.precision, got, tt.export)
+		}
+	}
+	if err := manager.SetExportPrecision("minutes"); err == nil {
+		t.Error("SetExportPrecision accepted an unknown precision")
+	}
+}
+
+func TestRepeatedStartsLeaveARunningTimerAlone(t *testing.T) {
+	manager := NewChronoManager(1)
+	manager.StartChronometer(0)
+	first, _ := manager.copyOf(0)
+
+	for i := 0; i < 100; i++ {
+		manager.StartChronometer(0)
+	}
+	c, _ := manager.copyOf(0)
+	if !c.startTime.Equal(first.startTime) || !c.startedAt.Equal(first.startedAt) {
+		t.Errorf("start moved from %v to %v", first.startTime, c.startTime)
+	}
+	if c.startCount != 1 || len(c.segments) != 1 {
+		t.Errorf("%d starts and %d segments after repeated starts, want 1 each", c.startCount, len(c.segments))
+	}
+}
+
+func TestTimerView(t *testing.T) {
+	manager := NewChronoManager(2)
+	manager.RenameChronometer(1, "review")
+	manager.SetElapsed(1, 90*time.Second)
+	view := NewTimerView(tview.NewApplication(), manager, 1)
+	style := TimerViewStyle{Digits: 0, Indicator: statusIndicators["symbols"], Theme: themes["dark"], TitleLabels: true}
+
+	view.Update(style, false)
+	if got := view.time.GetText(true); got != "00:01:30" {
+		t.Errorf("time %q, want 00:01:30", got)
+	}
+	if got := view.GetTitle(); !strings.Contains(got, "Timer 2: review") || !strings.Contains(got, tview.Escape("[---]")) {
+		t.Errorf("title %q", got)
+	}
+	if got := view.LabelField().GetText(); got != "review" {
+		t.Errorf("label field %q", got)
+	}
+
+	// The buttons act on the view's own timer
+	press := func(p tview.Primitive) {
+		p.InputHandler()(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), func(tview.Primitive) {})
+	}
+	press(view.Controls())
+	view.Update(style, false)
+	if c, _ := manager.copyOf(1); !c.isRunning {
+		t.Error("Start did not start timer 2")
+	}
+	if c, _ := manager.copyOf(0); c.isRunning {
+		t.Error("Start started timer 1")
+	}
+	if got := view.status.GetText(true); !strings.HasPrefix(got, "Status: [RUN] Running") {
+		t.Errorf("status %q", got)
+	}
+
+	// While the label is edited only the time is redrawn
+	manager.StopChronometer(1)
+	view.Update(style, true)
+	if got := view.status.GetText(true); !strings.HasPrefix(got, "Status: [RUN] Running") {
+		t.Errorf("status redrawn while editing: %q", got)
+	}
+
+	if !view.ToggleSelected() {
+		t.Error("ToggleSelected did not select")
+	}
+	if c, _ := manager.copyOf(1); !c.selected {
+		t.Error("the timer was not selected")
+	}
+	manager.RenameChronometer(1, "renamed")
+	view.Refresh()
+	if got := view.LabelField().GetText(); got != "renamed" {
+		t.Errorf("label field %q after Refresh", got)
+	}
+	if id := view.ChronoID(); id != 2 {
+		t.Errorf("ChronoID() = %d, want 2", id)
+	}
+
+	// Focusing any of the view's widgets makes its timer current
+	focus := func(p tview.Primitive) { p.Focus(func(tview.Primitive) {}) }
+	for name, p := range map[string]tview.Primitive{
+		"label": view.LabelField(), "buttons": view.Controls(), "checkbox": view.selected,
+	} {
+		manager.SetCurrent(0)
+		focus(p)
+		if got := manager.Current(); got != 1 {
+			t.Errorf("focusing the %s: Current() = %d, want 1", name, got)
+		}
+	}
+}
+
+func TestOnAutoSave(t *testing.T) {
+	manager := NewChronoManager(1)
+	var errs []error
+	manager.OnAutoSave(func(err error) {
+		// Callbacks may call back into the manager
+		if _, last := manager.LastAutoSave(); last != err {
+			t.Errorf("LastAutoSave error %v, callback given %v", last, err)
+		}
+		errs = append(errs, err)
+	})
+
+	dir := t.TempDir()
+	manager.autoSave(filepath.Join(dir, "autosave.json"))
+	manager.autoSave(filepath.Join(dir, "missing", "autosave.json"))
+	if len(errs) != 2 || errs[0] != nil || errs[1] == nil {
+		t.Errorf("callbacks given %v, want nil then an error", errs)
+	}
+}
+
+func TestStateChangesReachCallbacks(t *testing.T) {
+	manager := NewChronoManager(2)
+	var events []string
+	manager.OnStateChange(func(id int, event string, at time.Time) {
+		events = append(events, strconv.Itoa(id)+" "+event)
+	})
+
+	steps := []struct {
+		name   string
+		change func()
+		want   []string
+	}{
+		{"tap", func() { manager.Tap(0) }, []string{"1 reset", "1 start"}},
+		{"bulk stop", func() { manager.BulkSet([]int{0}, (*Chronometer).Stop) }, []string{"1 stop"}},
+		{"pomodoro", func() { manager.StartPomodoro(1) }, []string{"2 start"}},
+		{"skip phase", func() { manager.SkipPomodoroPhase(1) }, []string{"2 stop", "2 start"}},
+	}
+	for _, s := range steps {
+		events = nil
+		s.change()
+		if !reflect.DeepEqual(events, s.want) {
+			t.Errorf("%s: events %v, want %v", s.name, events, s.want)
+		}
+	}
+}
+
+func TestSaveToCSVTotal(t *testing.T) {
+	manager := NewChronoManager(2)
+	manager.SetElapsed(0, time.Minute)
+	manager.BulkSet([]int{1}, func(c *Chronometer) { c.SetTarget(10 * time.Minute) })
+	manager.SetElapsed(1, 4*time.Minute)
+
+	filename := filepath.Join(t.TempDir(), "export.csv")
+	if err := manager.SaveToCSV(filename); err != nil {
+		t.Fatal(err)
+	}
+	records, columns := readCSV(t, filename)
+
+	// The countdown row shows the 6 minutes left, and the total adds up
+	// the rows
+	footer := records[len(records)-1]
+	if got := records[2][columns["Elapsed Time"]]; got != "00:06:00.000" {
+		t.Errorf("countdown row %q, want 00:06:00.000", got)
+	}
+	if footer[columns["Label"]] != "Total" || footer[columns["Elapsed Time"]] != "00:07:00.000" {
+		t.Errorf("footer %q", footer)
+	}
+	if got := footer[columns["Elapsed (ns)"]]; got != strconv.FormatInt(int64(7*time.Minute), 10) {
+		t.Errorf("footer raw total %q", got)
+	}
+}
+
+func TestLapSplits(t *testing.T) {
+	manager := NewChronoManager(2)
+	manager.SetElapsed(0, 10*time.Second)
+	manager.LapChronometer(0)
+	manager.SetElapsed(0, 25*time.Second)
+	manager.LapChronometer(0)
+
+	c, _ := manager.Get(1)
+	if laps, splits := c.GetLaps(), c.GetSplits(); !reflect.DeepEqual(laps, []time.Duration{10 * time.Second, 15 * time.Second}) ||
+		!reflect.DeepEqual(splits, []time.Duration{10 * time.Second, 25 * time.Second}) {
+		t.Errorf("laps %v and splits %v", laps, splits)
+	}
+
+	filename := filepath.Join(t.TempDir(), "timers.json")
+	if err := manager.SaveToFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewChronoManager(2)
+	if err := loaded.LoadFromFile(filename, false); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := loaded.Get(1); !reflect.DeepEqual(c.GetSplits(), []time.Duration{10 * time.Second, 25 * time.Second}) {
+		t.Errorf("loaded splits %v", c.GetSplits())
+	}
+
+	manager.SetElapsed(1, 5*time.Second)
+	manager.LapChronometer(1)
+	if err := manager.MergeTimers(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := manager.Get(1); !reflect.DeepEqual(c.GetSplits(), []time.Duration{10 * time.Second, 25 * time.Second, 5 * time.Second}) {
+		t.Errorf("merged splits %v", c.GetSplits())
+	}
+
+	manager.ResetChronometer(0)
+	if c, _ := manager.Get(1); len(c.GetSplits()) != 0 {
+		t.Errorf("splits %v left after a reset", c.GetSplits())
+	}
+}
+
+func TestGridColumns(t *testing.T) {
+	tests := []struct{ n, want int }{
+		{0, 1}, {1, 1}, {2, 2}, {3, 3}, {9, 3}, {15, 3}, {16, 4}, {24, 4}, {25, 5}, {100, 10},
+	}
+	for _, tt := range tests {
+		if got := gridColumns(tt.n); got != tt.want {
+			t.Errorf("gridColumns(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRunHeadless(t *testing.T) {
+	manager := NewChronoManager(3)
+	if err := manager.RemoveChronometer(1); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := runHeadless(manager, strings.NewReader("# timer IDs, not positions\nstart 3\n\nlap 3\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	var timers []ExportTimer
+	if err := json.Unmarshal([]byte(out.String()), &timers); err != nil {
+		t.Fatal(err)
+	}
+	if len(timers) != 2 || timers[0].ID != 2 || timers[0].Running || timers[1].ID != 3 || !timers[1].Running {
+		t.Errorf("after start 3: %+v", timers)
+	}
+
+	tests := []struct {
+		script string
+		line   int
+		is     error
+	}{
+		{"stop 3\nstart x\n", 2, nil},
+		{"stop 1\n", 1, ErrTimerNotFound},
+	}
+	for _, tt := range tests {
+		err := runHeadless(manager, strings.NewReader(tt.script), io.Discard)
+		if tt.is != nil {
+			if !errors.Is(err, tt.is) || !strings.HasPrefix(err.Error(), "line "+strconv.Itoa(tt.line)+":") {
+				t.Errorf("%q: %v", tt.script, err)
+			}
+			continue
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Line != tt.line || pe.Raw != "x" {
+			t.Errorf("%q: %v, want a ParseError on line %d", tt.script, err, tt.line)
+		}
+	}
+}
+
+func TestListSaveFiles(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewChronoManager(3)
+	manager.SetElapsed(0, time.Minute)
+	manager.LapChronometer(0)
+	manager.AddNote(0, "multi\nline")
+	for name, save := range map[string]func(string) error{
+		"timers.json":  manager.SaveToFile,
+		"timers.yaml":  manager.SaveToYAML,
+		"timers.csv":   manager.SaveToCSV,
+		"export.json":  manager.ExportJSON,
+		"laps.csv":     manager.SaveLapsToCSV,
+		"timers.bin":   manager.SaveToGob,
+		"notes.md":     manager.ExportMarkdown,
+		"partial.json": func(string) error { return nil },
+	} {
+		if err := save(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "partial.json"), []byte(`{"chronometers": [{"id": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ListSaveFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]SaveFileInfo{}
+	for _, file := range files {
+		got[file.Name] = file
+	}
+	if len(got) != 3 {
+		t.Errorf("listed %v, want the JSON, YAML and CSV saves", files)
+	}
+	for _, name := range []string{"timers.json", "timers.yaml", "timers.csv"} {
+		file := got[name]
+		if file.Timers != 3 || file.MoreTimers {
+			t.Errorf("%s: %d timers", name, file.Timers)
+		}
+		if name != "timers.csv" && time.Since(file.SaveTime) > time.Minute {
+			t.Errorf("%s: saved %v", name, file.SaveTime)
+		}
+	}
+}
+
+func TestListSaveFilesStopsCounting(t *testing.T) {
+	dir := t.TempDir()
+	var csvData, jsonData strings.Builder
+	csvData.WriteString("Timer ID,Label,Elapsed Time\n")
+	jsonData.WriteString(`{"chronometers": [{}`)
+	for i := 0; i <= maxListedTimers; i++ {
+		csvData.WriteString("1,a,00:00:00\n")
+		jsonData.WriteString(`,{}`)
+	}
+	jsonData.WriteString(`], "saveTime": "2026-01-02T03:04:05Z"}`)
+	os.WriteFile(filepath.Join(dir, "big.csv"), []byte(csvData.String()), 0644)
+	os.WriteFile(filepath.Join(dir, "big.json"), []byte(jsonData.String()), 0644)
+
+	files, err := ListSaveFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("listed %v", files)
+	}
+	for _, file := range files {
+		if file.Timers != maxListedTimers || !file.MoreTimers {
+			t.Errorf("%s: %d timers, more %v", file.Name, file.Timers, file.MoreTimers)
+		}
+	}
+}
+
+func TestStopPastMaxDuration(t *testing.T) {
+	c := NewChronometer(1)
+	c.SetMaxDuration(time.Minute)
+	c.Start()
+	// The watcher hasn't stopped it yet when it is stopped by hand
+	start := time.Now().Add(-90 * time.Second)
+	c.startTime, c.segments[0].Start = start, start
+	c.Stop()
+
+	end := start.Add(time.Minute)
+	if c.elapsed() != time.Minute || !c.stoppedAt.Equal(end) {
+		t.Errorf("stopped at %v after %v, want %v after 1m0s", c.stoppedAt, c.elapsed(), end)
+	}
+	if seg := c.segments[0]; !seg.End.Equal(end) {
+		t.Errorf("segment ends at %v, want %v", seg.End, end)
+	}
+	if n := len(c.changes); n == 0 || !c.changes[n-1].at.Equal(end) {
+		t.Errorf("stop reported at %v, want %v", c.changes, end)
+	}
+}